@@ -0,0 +1,40 @@
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sb-go-readrate-nabiel/repository"
+	"time"
+)
+
+// StartPurgeScheduler erases accounts whose deletion grace period has passed, immediately and then
+// on every interval until ctx is canceled or the returned stop function is called. stop cancels a
+// purge in progress and waits for it to return; accounts not yet erased are picked up next start.
+func StartPurgeScheduler(ctx context.Context, db *sql.DB, interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			erased, problem := repository.PurgeDueAccounts(ctx, db)
+			if problem.Message != "" && ctx.Err() == nil {
+				slog.Error("failed to purge deleted accounts", "error", problem.Message)
+			}
+			if erased > 0 {
+				slog.Info("erased accounts after their deletion grace period", "count", erased)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-finished
+	}
+}
@@ -0,0 +1,164 @@
+package charts
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sb-go-readrate-nabiel/repository"
+	"sb-go-readrate-nabiel/structs"
+	"sort"
+	"time"
+)
+
+const (
+	TopRated = "top_rated"
+	Trending = "trending"
+)
+
+// Options controls how the charts are computed and how often they are refreshed.
+type Options struct {
+	RefreshInterval time.Duration
+	MinimumReviews  int           // "m" in the Bayesian weighted rating
+	TrendingWindow  time.Duration // Sliding window used for review velocity
+}
+
+// DefaultOptions returns the settings used when nothing is configured.
+func DefaultOptions() Options {
+	return Options{
+		RefreshInterval: 15 * time.Minute,
+		MinimumReviews:  5,
+		TrendingWindow:  7 * 24 * time.Hour,
+	}
+}
+
+// BayesianRating weighs a book's average rating towards the mean rating of its scope, so a book
+// with one 5-star review does not outrank a book with hundreds of good reviews:
+//
+//	WR = (v / (v + m)) * R + (m / (v + m)) * C
+func BayesianRating(reviewCount int, averageRating float64, minimumReviews int, meanRating float64) float64 {
+	v := float64(reviewCount)
+	m := float64(minimumReviews)
+	if v+m == 0 {
+		return 0
+	}
+	return (v/(v+m))*averageRating + (m/(v+m))*meanRating
+}
+
+// Compute builds every chart (across all categories and per category) from the given stats.
+func Compute(stats []structs.BookRatingStat, options Options, computedAt time.Time) []structs.ChartEntry {
+	byCategory := map[int][]structs.BookRatingStat{0: stats}
+	for _, stat := range stats {
+		byCategory[stat.CategoryID] = append(byCategory[stat.CategoryID], stat)
+	}
+
+	var entries []structs.ChartEntry
+	for categoryID, scoped := range byCategory {
+		entries = append(entries, rankTopRated(scoped, categoryID, options, computedAt)...)
+		entries = append(entries, rankTrending(scoped, categoryID, options, computedAt)...)
+	}
+	return entries
+}
+
+func rankTopRated(stats []structs.BookRatingStat, categoryID int, options Options, computedAt time.Time) []structs.ChartEntry {
+	totalReviews := 0
+	ratingSum := 0.0
+	for _, stat := range stats {
+		totalReviews += stat.ReviewCount
+		ratingSum += stat.AverageRating * float64(stat.ReviewCount)
+	}
+	meanRating := 0.0
+	if totalReviews > 0 {
+		meanRating = ratingSum / float64(totalReviews)
+	}
+
+	entries := make([]structs.ChartEntry, 0, len(stats))
+	for _, stat := range stats {
+		entries = append(entries, newEntry(TopRated, categoryID, stat,
+			BayesianRating(stat.ReviewCount, stat.AverageRating, options.MinimumReviews, meanRating), computedAt))
+	}
+	return assignRanks(entries)
+}
+
+func rankTrending(stats []structs.BookRatingStat, categoryID int, options Options, computedAt time.Time) []structs.ChartEntry {
+	windowDays := options.TrendingWindow.Hours() / 24
+	if windowDays <= 0 {
+		return nil
+	}
+
+	var entries []structs.ChartEntry
+	for _, stat := range stats {
+		if stat.RecentCount == 0 {
+			continue
+		}
+		// Review velocity: reviews per day inside the sliding window
+		entries = append(entries, newEntry(Trending, categoryID, stat, float64(stat.RecentCount)/windowDays, computedAt))
+	}
+	return assignRanks(entries)
+}
+
+func newEntry(chart string, categoryID int, stat structs.BookRatingStat, score float64, computedAt time.Time) structs.ChartEntry {
+	return structs.ChartEntry{
+		Chart:         chart,
+		CategoryID:    categoryID,
+		BookID:        stat.BookID,
+		Score:         score,
+		ReviewCount:   stat.ReviewCount,
+		AverageRating: stat.AverageRating,
+		ComputedAt:    computedAt,
+	}
+}
+
+// assignRanks orders entries by score, breaking ties by review count and then book ID so the
+// ranking is stable between refreshes.
+func assignRanks(entries []structs.ChartEntry) []structs.ChartEntry {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Score != entries[j].Score {
+			return entries[i].Score > entries[j].Score
+		}
+		if entries[i].ReviewCount != entries[j].ReviewCount {
+			return entries[i].ReviewCount > entries[j].ReviewCount
+		}
+		return entries[i].BookID < entries[j].BookID
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries
+}
+
+// Refresh recomputes and stores every chart.
+func Refresh(ctx context.Context, db *sql.DB, options Options) (problem structs.Error) {
+	now := time.Now()
+	stats, problem := repository.RetrieveBookRatingStats(ctx, db, now.Add(-options.TrendingWindow))
+	if problem.Message != "" {
+		return problem
+	}
+	return repository.ReplaceChartEntries(ctx, db, Compute(stats, options, now))
+}
+
+// StartScheduler refreshes the charts immediately and then on every RefreshInterval until ctx is
+// canceled or the returned stop function is called. stop cancels a refresh in progress, which
+// leaves the previous charts in place, and waits for it to return.
+func StartScheduler(ctx context.Context, db *sql.DB, options Options) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(options.RefreshInterval)
+		defer ticker.Stop()
+		for {
+			if problem := Refresh(ctx, db, options); problem.Message != "" && ctx.Err() == nil {
+				slog.Error("failed to refresh charts", "error", problem.Message)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-finished
+	}
+}
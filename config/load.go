@@ -0,0 +1,303 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// setting ties a Config field to its key in the config file, its environment variable and its
+// command-line flag. The flag name is the key with dots and underscores turned into dashes.
+type setting struct {
+	key    string // e.g. "database.host"
+	env    string
+	secret bool // Can also be read from the file named by <env>_FILE
+	target any  // *string, *[]string (comma-separated), *int, *float64 or *time.Duration
+	usage  string
+}
+
+func (s setting) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+func settings(c *Config) []setting {
+	return []setting{
+		{key: "port", env: "PORT", target: &c.Port, usage: "HTTP port"},
+		{key: "server.read_header_timeout", env: "SERVER_READ_HEADER_TIMEOUT", target: &c.Server.ReadHeaderTimeout, usage: "time allowed to read request headers"},
+		{key: "server.read_timeout", env: "SERVER_READ_TIMEOUT", target: &c.Server.ReadTimeout, usage: "time allowed to read a whole request"},
+		{key: "server.write_timeout", env: "SERVER_WRITE_TIMEOUT", target: &c.Server.WriteTimeout, usage: "time allowed to write a response"},
+		{key: "server.idle_timeout", env: "SERVER_IDLE_TIMEOUT", target: &c.Server.IdleTimeout, usage: "how long idle keep-alive connections stay open"},
+		{key: "server.shutdown_grace_period", env: "SHUTDOWN_GRACE_PERIOD", target: &c.Server.ShutdownGracePeriod, usage: "time in-flight requests get to finish on shutdown"},
+		{key: "server.trusted_proxies", env: "TRUSTED_PROXIES", target: &c.Server.TrustedProxies, usage: "comma-separated IPs or CIDRs of proxies whose X-Forwarded-For is believed"},
+
+		{key: "log.level", env: "LOG_LEVEL", target: &c.Log.Level, usage: "debug, info, warn or error"},
+		{key: "log.format", env: "LOG_FORMAT", target: &c.Log.Format, usage: "json or text"},
+
+		{key: "tracing.exporter", env: "TRACING_EXPORTER", target: &c.Tracing.Exporter, usage: "none, otlp, stdout or file"},
+		{key: "tracing.otlp_endpoint", env: "TRACING_OTLP_ENDPOINT", target: &c.Tracing.OTLPEndpoint, usage: "OTLP/HTTP traces URL, e.g. http://localhost:4318/v1/traces"},
+		{key: "tracing.file", env: "TRACING_FILE", target: &c.Tracing.FilePath, usage: "file the file exporter appends spans to"},
+		{key: "tracing.service_name", env: "OTEL_SERVICE_NAME", target: &c.Tracing.ServiceName, usage: "service name reported with spans"},
+		{key: "tracing.sample_ratio", env: "TRACING_SAMPLE_RATIO", target: &c.Tracing.SampleRatio, usage: "share of new traces recorded, 0 to 1"},
+
+		{key: "database.url", env: "DATABASE_URL", secret: true, target: &c.Database.URL, usage: "PostgreSQL URL; replaces the other database settings"},
+		{key: "database.host", env: "PGHOST", target: &c.Database.Host, usage: "PostgreSQL host"},
+		{key: "database.port", env: "PGPORT", target: &c.Database.Port, usage: "PostgreSQL port"},
+		{key: "database.user", env: "PGUSER", target: &c.Database.User, usage: "PostgreSQL user"},
+		{key: "database.password", env: "PGPASSWORD", secret: true, target: &c.Database.Password, usage: "PostgreSQL password"},
+		{key: "database.name", env: "PGDATABASE", target: &c.Database.Name, usage: "PostgreSQL database"},
+		{key: "database.sslmode", env: "PGSSLMODE", target: &c.Database.SSLMode, usage: "PostgreSQL sslmode, e.g. disable or require"},
+		{key: "database.query_timeout", env: "DATABASE_QUERY_TIMEOUT", target: &c.Database.QueryTimeout, usage: "time the queries of one repository call may take (0 disables)"},
+		{key: "database.job_timeout", env: "DATABASE_JOB_TIMEOUT", target: &c.Database.JobTimeout, usage: "time the queries of one background job step, such as a chart refresh, may take (0 disables)"},
+
+		{key: "charts.refresh_interval", env: "CHART_REFRESH_INTERVAL", target: &c.Charts.RefreshInterval, usage: "how often charts are recomputed"},
+		{key: "charts.min_reviews", env: "CHART_MIN_REVIEWS", target: &c.Charts.MinimumReviews, usage: "reviews a book needs to be ranked"},
+		{key: "charts.trending_window", env: "CHART_TRENDING_WINDOW", target: &c.Charts.TrendingWindow, usage: "window of the trending chart"},
+
+		{key: "accounts.deletion_grace_period", env: "ACCOUNT_DELETION_GRACE_PERIOD", target: &c.Accounts.DeletionGracePeriod, usage: "delay before a deleted account is erased"},
+		{key: "accounts.purge_interval", env: "ACCOUNT_PURGE_INTERVAL", target: &c.Accounts.PurgeInterval, usage: "how often due accounts are erased"},
+
+		{key: "reviews.report_hide_threshold", env: "REVIEW_REPORT_HIDE_THRESHOLD", target: &c.Reviews.ReportHideThreshold, usage: "open reports that hide a review (0 disables)"},
+		{key: "reviews.filter_config", env: "REVIEW_FILTER_CONFIG", target: &c.Reviews.FilterConfig, usage: "path of the review content filter rules"},
+
+		{key: "rating.min", env: "RATING_MIN", target: &c.Rating.Min, usage: "lowest rating"},
+		{key: "rating.max", env: "RATING_MAX", target: &c.Rating.Max, usage: "highest rating"},
+		{key: "rating.step", env: "RATING_STEP", target: &c.Rating.Step, usage: "step between ratings"},
+
+		{key: "credentials.username_min_length", env: "USERNAME_MIN_LENGTH", target: &c.Credentials.UsernameMinLength, usage: "shortest username"},
+		{key: "credentials.username_max_length", env: "USERNAME_MAX_LENGTH", target: &c.Credentials.UsernameMaxLength, usage: "longest username"},
+		{key: "credentials.username_pattern", env: "USERNAME_PATTERN", target: &c.Credentials.UsernamePattern, usage: "regular expression usernames must match"},
+		{key: "credentials.password_min_length", env: "PASSWORD_MIN_LENGTH", target: &c.Credentials.PasswordMinLength, usage: "shortest password"},
+		{key: "credentials.password_min_classes", env: "PASSWORD_MIN_CLASSES", target: &c.Credentials.PasswordMinClasses, usage: "character classes a password needs"},
+		{key: "credentials.common_passwords_file", env: "COMMON_PASSWORDS_FILE", target: &c.Credentials.CommonPasswordsFile, usage: "list of rejected passwords"},
+
+		{key: "mail.driver", env: "MAILER_DRIVER", target: &c.Mail.Driver, usage: "smtp, file or log"},
+		{key: "mail.from", env: "MAIL_FROM", target: &c.Mail.From, usage: "sender address"},
+		{key: "mail.smtp_host", env: "SMTP_HOST", target: &c.Mail.Host, usage: "SMTP host"},
+		{key: "mail.smtp_port", env: "SMTP_PORT", target: &c.Mail.Port, usage: "SMTP port"},
+		{key: "mail.smtp_username", env: "SMTP_USERNAME", target: &c.Mail.Username, usage: "SMTP username"},
+		{key: "mail.smtp_password", env: "SMTP_PASSWORD", secret: true, target: &c.Mail.Password, usage: "SMTP password"},
+		{key: "mail.file", env: "MAIL_FILE", target: &c.Mail.FilePath, usage: "file the file mailer appends to"},
+
+		{key: "tokens.password_reset_ttl", env: "PASSWORD_RESET_TOKEN_TTL", target: &c.Tokens.PasswordResetTTL, usage: "lifetime of password reset tokens"},
+		{key: "tokens.email_verification_ttl", env: "EMAIL_VERIFICATION_TOKEN_TTL", target: &c.Tokens.EmailVerificationTTL, usage: "lifetime of email verification tokens"},
+		{key: "tokens.session_ttl", env: "SESSION_TTL", target: &c.Tokens.SessionTTL, usage: "lifetime of session tokens from POST /login"},
+
+		{key: "login.max_failures", env: "LOGIN_MAX_FAILURES", target: &c.Login.MaxUsernameFailures, usage: "failures before an account is locked"},
+		{key: "login.max_ip_failures", env: "LOGIN_MAX_IP_FAILURES", target: &c.Login.MaxIPFailures, usage: "failures before a client IP is locked"},
+		{key: "login.lockout_duration", env: "LOGIN_LOCKOUT_DURATION", target: &c.Login.LockoutDuration, usage: "length of a lockout"},
+		{key: "login.backoff_base", env: "LOGIN_BACKOFF_BASE", target: &c.Login.BaseDelay, usage: "wait after the first failure"},
+		{key: "login.backoff_max", env: "LOGIN_BACKOFF_MAX", target: &c.Login.MaxDelay, usage: "longest wait between failures"},
+	}
+}
+
+// Load builds the configuration from the defaults, then the config file, then environment
+// variables, then command-line flags, each overriding the one before. Environment variables
+// deliberately win over the file, which is usually shared by every deployment, so a deployment can
+// override it without editing it. The config file is named by the -config flag or CONFIG_FILE and
+// may be YAML (.yaml, .yml) or TOML (.toml). args are the command-line arguments without the
+// program name. The result is validated.
+func Load(args []string, output io.Writer) (Config, error) {
+	c := Default()
+	table := settings(&c)
+
+	flags := flag.NewFlagSet("readrate", flag.ContinueOnError)
+	flags.SetOutput(output)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML config file (env CONFIG_FILE)")
+	flagValues := map[string]*string{}
+	for _, s := range table {
+		flagValues[s.key] = flags.String(s.flagName(), "", fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	if err := flags.Parse(args); err != nil {
+		return c, err
+	}
+	if flags.NArg() > 0 {
+		return c, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	var problems []error
+
+	if *configFile != "" {
+		fileValues, err := readFile(*configFile)
+		if err != nil {
+			return c, err
+		}
+		for _, s := range table {
+			if value, ok := fileValues[s.key]; ok {
+				problems = append(problems, apply(s, value, "key "+s.key+" in "+*configFile))
+				delete(fileValues, s.key)
+			}
+		}
+		for _, key := range sortedKeys(fileValues) {
+			problems = append(problems, fmt.Errorf("unknown key %s in %s", key, *configFile))
+		}
+	}
+
+	for _, s := range table {
+		value, ok, err := lookupEnv(s)
+		if err != nil {
+			problems = append(problems, err)
+		} else if ok {
+			problems = append(problems, apply(s, value, "environment variable "+s.env))
+		}
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range table {
+			if s.flagName() == f.Name {
+				problems = append(problems, apply(s, *flagValues[s.key], "flag -"+f.Name))
+			}
+		}
+	})
+
+	if err := errors.Join(problems...); err != nil {
+		return c, err
+	}
+	return c, c.Validate()
+}
+
+// lookupEnv reads the environment variable of a setting; an empty variable counts as unset.
+// Secrets can instead be read from the file named by <env>_FILE, such as a mounted Docker or
+// Kubernetes secret.
+func lookupEnv(s setting) (value string, ok bool, err error) {
+	value = os.Getenv(s.env)
+	ok = value != ""
+	if !s.secret {
+		return value, ok, nil
+	}
+
+	path := os.Getenv(s.env + "_FILE")
+	if path == "" {
+		return value, ok, nil
+	}
+	if ok {
+		return "", false, fmt.Errorf("%s and %s_FILE cannot both be set", s.env, s.env)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s_FILE: %w", s.env, err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), true, nil
+}
+
+// apply parses value into the field of a setting. source says where the value came from, for the
+// error message.
+func apply(s setting, value string, source string) error {
+	value = strings.TrimSpace(value)
+	var err error
+	switch target := s.target.(type) {
+	case *string:
+		*target = value
+	case *[]string:
+		*target = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*target = append(*target, item)
+			}
+		}
+	case *int:
+		*target, err = strconv.Atoi(value)
+		if err != nil {
+			err = fmt.Errorf("must be a whole number")
+		}
+	case *float64:
+		*target, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			err = fmt.Errorf("must be a number")
+		}
+	case *time.Duration:
+		*target, err = time.ParseDuration(value)
+		if err != nil {
+			err = fmt.Errorf(`must be a duration such as "90s", "15m" or "48h"`)
+		}
+	default:
+		err = fmt.Errorf("unsupported setting type %T", s.target)
+	}
+	if err != nil {
+		if s.secret {
+			return fmt.Errorf("invalid %s: %w", source, err)
+		}
+		return fmt.Errorf("invalid %s %q: %w", source, value, err)
+	}
+	return nil
+}
+
+// readFile decodes a YAML or TOML config file into values keyed like "database.host".
+func readFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	document := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		if err := decoder.Decode(&document); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	case ".toml":
+		if err := toml.Unmarshal(content, &document); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+
+	values := map[string]string{}
+	if err := flatten("", document, values); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+// flatten turns nested sections into dotted keys. Leaves are single values or lists of single
+// values, which are joined with commas.
+func flatten(prefix string, section map[string]any, values map[string]string) error {
+	for name, value := range section {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		switch typed := value.(type) {
+		case map[string]any:
+			if err := flatten(key, typed, values); err != nil {
+				return err
+			}
+		case []any:
+			items := make([]string, 0, len(typed))
+			for _, item := range typed {
+				switch item.(type) {
+				case map[string]any, []any, nil:
+					return fmt.Errorf("key %s must be a list of single values", key)
+				}
+				items = append(items, fmt.Sprint(item))
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+			// An empty value leaves the setting as it is
+		default:
+			values[key] = fmt.Sprint(typed)
+		}
+	}
+	return nil
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
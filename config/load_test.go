@@ -0,0 +1,179 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// isolate clears every environment variable Load reads, since an empty variable counts as unset,
+// and sets the database settings Validate requires.
+func isolate(t *testing.T) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	for _, s := range settings(&Config{}) {
+		t.Setenv(s.env, "")
+		if s.secret {
+			t.Setenv(s.env+"_FILE", "")
+		}
+	}
+	t.Setenv("PGHOST", "localhost")
+	t.Setenv("PGUSER", "readrate")
+	t.Setenv("PGDATABASE", "readrate")
+}
+
+// writeFile writes content to a file named name in a temporary directory and returns its path.
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	isolate(t)
+	configFile := writeFile(t, "readrate.yaml", `
+port: "7000"
+log:
+  level: warn
+database:
+  query_timeout: 2s
+  job_timeout: 10m
+`)
+	t.Setenv("LOG_LEVEL", "error")
+	t.Setenv("DATABASE_QUERY_TIMEOUT", "3s")
+
+	loaded, err := Load([]string{"-config", configFile, "-database-query-timeout", "4s"}, io.Discard)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"default when nothing is set", loaded.Database.SSLMode, "disable"},
+		{"file over default", loaded.Port, 7000},
+		{"file over default, nested", loaded.Database.JobTimeout, 10 * time.Minute},
+		{"environment over file", loaded.Log.Level, "error"},
+		{"flag over environment and file", loaded.Database.QueryTimeout, 4 * time.Second},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, test.got, test.want)
+		}
+	}
+}
+
+func TestLoadConfigFileFromEnvironment(t *testing.T) {
+	isolate(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, "readrate.toml", `
+[server]
+trusted_proxies = ["10.0.0.0/8", "192.168.1.1"]
+
+[login]
+max_failures = 7
+`))
+
+	loaded, err := Load(nil, io.Discard)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if loaded.Login.MaxUsernameFailures != 7 {
+		t.Errorf("login.max_failures = %d, want 7", loaded.Login.MaxUsernameFailures)
+	}
+	if want := []string{"10.0.0.0/8", "192.168.1.1"}; !slices.Equal(loaded.Server.TrustedProxies, want) {
+		t.Errorf("server.trusted_proxies = %q, want %q", loaded.Server.TrustedProxies, want)
+	}
+}
+
+func TestLoadSecretFromFile(t *testing.T) {
+	isolate(t)
+	t.Setenv("PGPASSWORD_FILE", writeFile(t, "pgpassword", "s3cret\n"))
+
+	loaded, err := Load(nil, io.Discard)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if loaded.Database.Password != "s3cret" {
+		t.Errorf("database password = %q, want the file content without the trailing newline", loaded.Database.Password)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		file    string
+		wantErr string
+	}{
+		{
+			name:    "secret and its file both set",
+			env:     map[string]string{"PGPASSWORD": "a", "PGPASSWORD_FILE": "/nonexistent"},
+			wantErr: "PGPASSWORD and PGPASSWORD_FILE cannot both be set",
+		},
+		{
+			name:    "missing secret file",
+			env:     map[string]string{"SMTP_PASSWORD_FILE": "/nonexistent/smtp"},
+			wantErr: "SMTP_PASSWORD_FILE:",
+		},
+		{
+			name:    "only secrets have a file variable",
+			env:     map[string]string{"PGHOST_FILE": "/nonexistent", "PGHOST": "db"},
+			wantErr: "",
+		},
+		{
+			name:    "invalid number in a flag",
+			args:    []string{"-mail-smtp-port", "many"},
+			wantErr: `invalid flag -mail-smtp-port "many": must be a whole number`,
+		},
+		{
+			name:    "invalid duration in the environment",
+			env:     map[string]string{"SESSION_TTL": "a day"},
+			wantErr: `invalid environment variable SESSION_TTL "a day": must be a duration`,
+		},
+		{
+			name:    "unknown key in the file",
+			file:    "databse:\n  host: db\n",
+			wantErr: "unknown key databse.host",
+		},
+		{
+			name:    "unexpected argument",
+			args:    []string{"serve"},
+			wantErr: `unexpected argument "serve"`,
+		},
+		{
+			name:    "validation runs after loading",
+			args:    []string{"-log-level", "loud"},
+			wantErr: `log level must be debug, info, warn or error, got "loud"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isolate(t)
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			args := test.args
+			if test.file != "" {
+				args = append(args, "-config", writeFile(t, "readrate.yml", test.file))
+			}
+
+			_, err := Load(args, io.Discard)
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Errorf("Load returned error: %v", err)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Errorf("Load returned error %v, want one containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"sb-go-readrate-nabiel/database"
+	"sb-go-readrate-nabiel/mailer"
+	"sb-go-readrate-nabiel/repository"
+	"sb-go-readrate-nabiel/structs"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Mailer sends password reset and email verification messages.
+var Mailer mailer.Mailer = mailer.LogMailer{}
+
+// PasswordResetTokenTTL and EmailVerificationTokenTTL are how long emailed tokens stay valid.
+var (
+	PasswordResetTokenTTL     = time.Hour
+	EmailVerificationTokenTTL = 48 * time.Hour
+)
+
+const maxEmailLength = 255
+
+// HandleChangePassword changes the authenticated user's password after checking the current one.
+func HandleChangePassword(context *gin.Context) {
+	responseCode := http.StatusOK
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	var passwordChange structs.PasswordChange
+	if bindError := context.ShouldBindJSON(&passwordChange); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	if problems := CredentialPolicy.CheckPassword(passwordChange.NewPassword, userRecord.Username); len(problems) > 0 {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "New password is invalid",
+			"errors": map[string][]string{"new_password": problems},
+		})
+		return
+	}
+
+	updateError := repository.ChangeUserPassword(context.Request.Context(), database.DbConnection, userRecord.ID, passwordChange.CurrentPassword, passwordChange.NewPassword)
+
+	if updateError.Message != "" {
+		responseCode = updateError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": updateError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Password changed successfully",
+	})
+}
+
+// HandleRequestPasswordReset emails a password reset token to the owner of a verified email
+// address. The response is the same whether or not the address belongs to an account.
+func HandleRequestPasswordReset(context *gin.Context) {
+	responseCode := http.StatusOK
+	var resetRequest structs.PasswordResetRequest
+	if bindError := context.ShouldBindJSON(&resetRequest); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	userRecord, retrievalError := repository.FindUserByEmail(context.Request.Context(), database.DbConnection, strings.TrimSpace(resetRequest.Email))
+
+	if retrievalError.Message != "" && retrievalError.Status != http.StatusNotFound {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	if retrievalError.Message == "" {
+		if mailError := sendPasswordResetEmail(context.Request.Context(), userRecord, false); mailError.Message != "" {
+			if mailError.Status != http.StatusBadGateway {
+				responseCode = mailError.Status
+				context.JSON(responseCode, gin.H{
+					"detail": mailError.Message,
+				})
+				return
+			}
+			slog.WarnContext(context.Request.Context(), "failed to send password reset email", "target_user_id", userRecord.ID, "error", mailError.Message)
+		}
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "If the email address belongs to an account and is verified, a password reset token has been sent to it",
+	})
+}
+
+// HandleConfirmPasswordReset sets a new password using an emailed password reset token.
+func HandleConfirmPasswordReset(context *gin.Context) {
+	responseCode := http.StatusOK
+	var confirmation structs.PasswordResetConfirmation
+	if bindError := context.ShouldBindJSON(&confirmation); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	tokenOwner, tokenError := repository.FindTokenOwner(context.Request.Context(), database.DbConnection, confirmation.Token, structs.TokenPasswordReset)
+	if tokenError.Message != "" {
+		responseCode = tokenError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": tokenError.Message,
+		})
+		return
+	}
+
+	if problems := CredentialPolicy.CheckPassword(confirmation.NewPassword, tokenOwner.Username); len(problems) > 0 {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "New password is invalid",
+			"errors": map[string][]string{"new_password": problems},
+		})
+		return
+	}
+
+	resetError := repository.ResetPasswordWithToken(context.Request.Context(), database.DbConnection, confirmation.Token, confirmation.NewPassword)
+
+	if resetError.Message != "" {
+		responseCode = resetError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": resetError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Password reset successfully",
+	})
+}
+
+// HandleVerifyEmail confirms an email address using an emailed verification token.
+func HandleVerifyEmail(context *gin.Context) {
+	responseCode := http.StatusOK
+	var verification structs.EmailVerification
+	if bindError := context.ShouldBindJSON(&verification); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	verificationError := repository.VerifyEmailWithToken(context.Request.Context(), database.DbConnection, verification.Token)
+
+	if verificationError.Message != "" {
+		responseCode = verificationError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": verificationError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Email verified successfully",
+	})
+}
+
+// HandleResendVerificationEmail sends a new verification token to the authenticated user's
+// unverified email address.
+func HandleResendVerificationEmail(context *gin.Context) {
+	responseCode := http.StatusOK
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	profile, retrievalError := repository.FindUserByID(context.Request.Context(), database.DbConnection, userRecord.ID)
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	if profile.Email == "" {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "You have no email address to verify",
+		})
+		return
+	}
+	if profile.EmailVerified {
+		responseCode = http.StatusConflict
+		context.JSON(responseCode, gin.H{
+			"detail": "Your email address is already verified",
+		})
+		return
+	}
+
+	if sendError := sendVerificationEmail(context.Request.Context(), profile.ID, profile.Username, profile.Email); sendError.Message != "" {
+		responseCode = sendError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": sendError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Verification email sent",
+	})
+}
+
+// sendVerificationEmail issues an email verification token and mails it to the given address.
+func sendVerificationEmail(ctx context.Context, userID int, username string, email string) (problem structs.Error) {
+	token, problem := repository.IssueUserToken(ctx, database.DbConnection, userID, structs.TokenEmailVerification, EmailVerificationTokenTTL)
+	if problem.Message != "" {
+		return problem
+	}
+
+	message := mailer.Message{
+		To:      email,
+		Subject: "Verify your ReadRate email address",
+		Body: fmt.Sprintf("Hi %s,\n\nUse this token to verify your email address (POST /verify-email):\n\n%s\n\n"+
+			"It expires in %s and can only be used once.\n",
+			username, token, EmailVerificationTokenTTL),
+	}
+	if err := Mailer.Send(message); err != nil {
+		return structs.Error{
+			Message: "failed to send verification email: " + err.Error(),
+			Status:  http.StatusBadGateway,
+		}
+	}
+	return
+}
+
+// sendPasswordResetEmail issues a password reset token for the user and emails it to them. forced
+// says the reset was required by an admin rather than requested by the user.
+func sendPasswordResetEmail(ctx context.Context, userRecord structs.User, forced bool) (problem structs.Error) {
+	token, problem := repository.IssueUserToken(ctx, database.DbConnection, userRecord.ID, structs.TokenPasswordReset, PasswordResetTokenTTL)
+	if problem.Message != "" {
+		return problem
+	}
+
+	reason := "If you did not ask for a reset, you can ignore this email."
+	if forced {
+		reason = "An administrator requires you to choose a new password before you can sign in again."
+	}
+
+	message := mailer.Message{
+		To:      userRecord.Email,
+		Subject: "Reset your ReadRate password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse this token to choose a new password (POST /password-reset/confirm):\n\n%s\n\n"+
+			"It expires in %s and can only be used once. %s\n",
+			userRecord.Username, token, PasswordResetTokenTTL, reason),
+	}
+	if err := Mailer.Send(message); err != nil {
+		return structs.Error{
+			Message: "failed to send password reset email: " + err.Error(),
+			Status:  http.StatusBadGateway,
+		}
+	}
+	return
+}
+
+// validEmail reports whether email is a single bare address such as "name@example.com".
+func validEmail(email string) bool {
+	if len(email) > maxEmailLength {
+		return false
+	}
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email && address.Name == ""
+}
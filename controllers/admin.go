@@ -0,0 +1,525 @@
+package controllers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sb-go-readrate-nabiel/database"
+	"sb-go-readrate-nabiel/loginguard"
+	"sb-go-readrate-nabiel/repository"
+	"sb-go-readrate-nabiel/structs"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginGuard is the failed-login tracker used by the authentication middleware.
+var LoginGuard *loginguard.Guard
+
+// HandleUnlockUser lifts a user's login lockout and clears their failed attempts.
+func HandleUnlockUser(context *gin.Context) {
+	responseCode := http.StatusOK
+	userID, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Invalid user ID",
+		})
+		return
+	}
+
+	userRecord, retrievalError := repository.FindUserByID(context.Request.Context(), database.DbConnection, userID)
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	adminID := 0
+	if currentUser, exists := context.Get("user"); exists {
+		if adminRecord, ok := currentUser.(structs.User); ok {
+			adminID = adminRecord.ID
+		}
+	}
+
+	message := "User had no failed login attempts"
+	if LoginGuard != nil && LoginGuard.Unlock(userRecord.Username) {
+		slog.InfoContext(context.Request.Context(), "account unlocked", "username", userRecord.Username, "target_user_id", userRecord.ID)
+		if auditError := repository.RecordAdminAction(context.Request.Context(), database.DbConnection, adminID, userRecord.ID, repository.AdminActionUnlock, ""); auditError.Message != "" {
+			slog.ErrorContext(context.Request.Context(), "failed to record admin action", "action", repository.AdminActionUnlock, "target_user_id", userRecord.ID, "error", auditError.Message)
+		}
+		message = "User unlocked successfully"
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": message,
+	})
+}
+
+// HandleGetRoleSettings lists the security policies of every role.
+func HandleGetRoleSettings(context *gin.Context) {
+	responseCode := http.StatusOK
+	settingsList, retrievalError := repository.RetrieveRoleSettings(context.Request.Context(), database.DbConnection)
+
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"items": settingsList,
+	})
+}
+
+// HandleUpdateRoleSettings changes the security policies of a role, such as requiring
+// two-factor authentication.
+func HandleUpdateRoleSettings(context *gin.Context) {
+	responseCode := http.StatusOK
+	role := context.Param("role")
+	if !slices.Contains(structs.Roles, role) {
+		responseCode = http.StatusNotFound
+		context.JSON(responseCode, gin.H{
+			"detail": "Unknown role " + strconv.Quote(role),
+		})
+		return
+	}
+
+	var policy struct {
+		RequireTwoFactor *bool `json:"require_two_factor" binding:"required"`
+	}
+	if bindError := context.ShouldBindJSON(&policy); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	adminRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	updateError := repository.UpdateRoleSettings(context.Request.Context(), database.DbConnection, structs.RoleSettings{
+		Role:             role,
+		RequireTwoFactor: *policy.RequireTwoFactor,
+		ModifiedBy:       adminRecord.ID,
+	})
+
+	if updateError.Message != "" {
+		responseCode = updateError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": updateError.Message,
+		})
+		return
+	}
+
+	detail := fmt.Sprintf("%s: require_two_factor=%t", role, *policy.RequireTwoFactor)
+	if auditError := repository.RecordAdminAction(context.Request.Context(), database.DbConnection, adminRecord.ID, 0, repository.AdminActionRoleSettings, detail); auditError.Message != "" {
+		slog.ErrorContext(context.Request.Context(), "failed to record admin action", "action", repository.AdminActionRoleSettings, "role", role, "error", auditError.Message)
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Role settings updated successfully",
+	})
+}
+
+// HandleGetUsers lists users a page at a time. The q parameter searches usernames, emails and
+// display names; role and status ("active" or "suspended") filter the list.
+func HandleGetUsers(context *gin.Context) {
+	responseCode := http.StatusOK
+	page, pageSize, ok := parsePagination(context)
+	if !ok {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Page must be a positive number and page_size must be between 1 and " + strconv.Itoa(maxPageSize),
+		})
+		return
+	}
+
+	search := strings.TrimSpace(context.Query("q"))
+	role := context.Query("role")
+	status := context.Query("status")
+	if role != "" && !slices.Contains(structs.Roles, role) {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Unknown role " + strconv.Quote(role),
+		})
+		return
+	}
+	if status != "" && status != "active" && status != "suspended" {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": `Status must be "active" or "suspended"`,
+		})
+		return
+	}
+
+	userList, total, retrievalError := repository.RetrieveUsers(context.Request.Context(), database.DbConnection, search, role, status, page, pageSize)
+
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"items":     userList,
+		"page":      page,
+		"page_size": pageSize,
+		"total":     total,
+	})
+}
+
+// HandleGetUser returns a user's full account record.
+func HandleGetUser(context *gin.Context) {
+	_, targetRecord, ok := adminTarget(context)
+	if !ok {
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"item": targetRecord,
+	})
+}
+
+// HandleGetUserActivity returns every review a user wrote, hidden ones included, along with their
+// votes, comments and reports.
+func HandleGetUserActivity(context *gin.Context) {
+	responseCode := http.StatusOK
+	_, targetRecord, ok := adminTarget(context)
+	if !ok {
+		return
+	}
+
+	reviewList, retrievalError := repository.RetrieveAllReviewsByUser(context.Request.Context(), database.DbConnection, targetRecord.ID)
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	activityList, retrievalError := repository.RetrieveUserActivity(context.Request.Context(), database.DbConnection, targetRecord.ID)
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	// Admins always see the raw text
+	for i := range reviewList {
+		presentReview(&reviewList[i], true)
+	}
+
+	context.JSON(responseCode, gin.H{
+		"reviews":  reviewList,
+		"activity": activityList,
+	})
+}
+
+// HandleSuspendUser stops a user from signing in or using their API keys until they are
+// unsuspended. The reason is shown to them when they try.
+func HandleSuspendUser(context *gin.Context) {
+	responseCode := http.StatusOK
+	adminRecord, targetRecord, ok := adminTarget(context)
+	if !ok {
+		return
+	}
+
+	var suspension structs.SuspensionRequest
+	if bindError := context.ShouldBindJSON(&suspension); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	if targetRecord.ID == adminRecord.ID {
+		responseCode = http.StatusForbidden
+		context.JSON(responseCode, gin.H{
+			"detail": "You cannot suspend your own account",
+		})
+		return
+	}
+
+	suspensionError := repository.SuspendUser(context.Request.Context(), database.DbConnection, targetRecord.ID, strings.TrimSpace(suspension.Reason), adminRecord.ID)
+
+	if suspensionError.Message != "" {
+		responseCode = suspensionError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": suspensionError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "User suspended successfully",
+	})
+}
+
+// HandleUnsuspendUser lets a suspended user sign in again.
+func HandleUnsuspendUser(context *gin.Context) {
+	responseCode := http.StatusOK
+	adminRecord, targetRecord, ok := adminTarget(context)
+	if !ok {
+		return
+	}
+
+	if !targetRecord.Suspended {
+		responseCode = http.StatusConflict
+		context.JSON(responseCode, gin.H{
+			"detail": "User is not suspended",
+		})
+		return
+	}
+
+	unsuspensionError := repository.UnsuspendUser(context.Request.Context(), database.DbConnection, targetRecord.ID, adminRecord.ID)
+
+	if unsuspensionError.Message != "" {
+		responseCode = unsuspensionError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": unsuspensionError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "User unsuspended successfully",
+	})
+}
+
+// HandleForcePasswordReset refuses a user's logins until they choose a new password, and emails
+// them a password reset token.
+func HandleForcePasswordReset(context *gin.Context) {
+	responseCode := http.StatusOK
+	adminRecord, targetRecord, ok := adminTarget(context)
+	if !ok {
+		return
+	}
+
+	// Without a verified email address the user would have no way to reset their password
+	if targetRecord.Email == "" || !targetRecord.EmailVerified {
+		responseCode = http.StatusConflict
+		context.JSON(responseCode, gin.H{
+			"detail": "User has no verified email address to send a password reset token to",
+		})
+		return
+	}
+
+	resetError := repository.RequirePasswordReset(context.Request.Context(), database.DbConnection, targetRecord.ID, adminRecord.ID)
+
+	if resetError.Message != "" {
+		responseCode = resetError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": resetError.Message,
+		})
+		return
+	}
+
+	message := "Password reset required; a reset token has been emailed to the user"
+	if mailError := sendPasswordResetEmail(context.Request.Context(), targetRecord, true); mailError.Message != "" {
+		slog.WarnContext(context.Request.Context(), "failed to send password reset email", "target_user_id", targetRecord.ID, "error", mailError.Message)
+		message = "Password reset required, but the reset email could not be sent; the user can request one through POST /password-reset"
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": message,
+	})
+}
+
+// HandleChangeUserRole gives a user another role.
+func HandleChangeUserRole(context *gin.Context) {
+	responseCode := http.StatusOK
+	adminRecord, targetRecord, ok := adminTarget(context)
+	if !ok {
+		return
+	}
+
+	var roleChange structs.RoleChange
+	if bindError := context.ShouldBindJSON(&roleChange); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	if !slices.Contains(structs.Roles, roleChange.Role) {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Unknown role " + strconv.Quote(roleChange.Role),
+		})
+		return
+	}
+
+	// Keeps an admin from demoting themselves and leaving no one to undo it
+	if targetRecord.ID == adminRecord.ID {
+		responseCode = http.StatusForbidden
+		context.JSON(responseCode, gin.H{
+			"detail": "You cannot change your own role",
+		})
+		return
+	}
+
+	changeError := repository.ChangeUserRole(context.Request.Context(), database.DbConnection, targetRecord.ID, roleChange.Role, adminRecord.ID)
+
+	if changeError.Message != "" {
+		responseCode = changeError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": changeError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "User role changed successfully",
+	})
+}
+
+// HandleAdminDeleteUser erases a user's account right away, without the grace period of
+// DELETE /me. Their reviews are deleted or anonymized as the payload asks.
+func HandleAdminDeleteUser(context *gin.Context) {
+	responseCode := http.StatusOK
+	adminRecord, targetRecord, ok := adminTarget(context)
+	if !ok {
+		return
+	}
+
+	var deletionRequest structs.AccountDeletionRequest
+	if bindError := context.ShouldBindJSON(&deletionRequest); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	if deletionRequest.Reviews != structs.ReviewsDelete && deletionRequest.Reviews != structs.ReviewsAnonymize {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": `Reviews must be "delete" or "anonymize"`,
+		})
+		return
+	}
+
+	if targetRecord.ID == adminRecord.ID {
+		responseCode = http.StatusForbidden
+		context.JSON(responseCode, gin.H{
+			"detail": "You cannot delete your own account here; use DELETE /me",
+		})
+		return
+	}
+
+	deletionError := repository.DeleteUserAsAdmin(context.Request.Context(), database.DbConnection, targetRecord.ID, deletionRequest.Reviews, adminRecord.ID)
+
+	if deletionError.Message != "" {
+		responseCode = deletionError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": deletionError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "User deleted successfully",
+	})
+}
+
+// HandleGetAdminActions lists the admin audit log a page at a time, newest first.
+func HandleGetAdminActions(context *gin.Context) {
+	responseCode := http.StatusOK
+	page, pageSize, ok := parsePagination(context)
+	if !ok {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Page must be a positive number and page_size must be between 1 and " + strconv.Itoa(maxPageSize),
+		})
+		return
+	}
+
+	actionList, total, retrievalError := repository.RetrieveAdminActions(context.Request.Context(), database.DbConnection, page, pageSize)
+
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"items":     actionList,
+		"page":      page,
+		"page_size": pageSize,
+		"total":     total,
+	})
+}
+
+// adminTarget reads the acting admin and the user named by the :id parameter. When either cannot
+// be found it responds with the error itself and returns ok false.
+func adminTarget(context *gin.Context) (adminRecord structs.User, targetRecord structs.User, ok bool) {
+	userID, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"detail": "Invalid user ID",
+		})
+		return
+	}
+
+	currentUser, exists := context.Get("user")
+	if !exists {
+		context.JSON(http.StatusUnauthorized, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	adminRecord, ok = currentUser.(structs.User)
+	if !ok {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	targetRecord, retrievalError := repository.FindUserByID(context.Request.Context(), database.DbConnection, userID)
+	if retrievalError.Message != "" {
+		context.JSON(retrievalError.Status, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return adminRecord, targetRecord, false
+	}
+	return adminRecord, targetRecord, true
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"net/http"
+	"sb-go-readrate-nabiel/database"
+	"sb-go-readrate-nabiel/repository"
+	"sb-go-readrate-nabiel/structs"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxAPIKeyNameLength = 100
+
+// HandleCreateAPIKey creates a named, scoped API key for the authenticated user. The key is only
+// included in this response.
+func HandleCreateAPIKey(context *gin.Context) {
+	responseCode := http.StatusOK
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	var newKey structs.APIKey
+	if bindError := context.ShouldBindJSON(&newKey); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	newKey.Name = strings.TrimSpace(newKey.Name)
+	if newKey.Name == "" || utf8.RuneCountInString(newKey.Name) > maxAPIKeyNameLength {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "API key name must be between 1 and " + strconv.Itoa(maxAPIKeyNameLength) + " characters",
+		})
+		return
+	}
+
+	scopes := []string{}
+	for _, scope := range newKey.Scopes {
+		if !slices.Contains(structs.Scopes, scope) {
+			responseCode = http.StatusBadRequest
+			context.JSON(responseCode, gin.H{
+				"detail": "Unknown scope " + strconv.Quote(scope) + "; use " + strings.Join(structs.Scopes, ", "),
+			})
+			return
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "An API key needs at least one scope",
+		})
+		return
+	}
+
+	newKey.UserID = userRecord.ID
+	newKey.Scopes = scopes
+
+	createdKey, key, creationError := repository.StoreAPIKey(context.Request.Context(), database.DbConnection, newKey)
+
+	if creationError.Message != "" {
+		responseCode = creationError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": creationError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "API key created; copy it now, it will not be shown again",
+		"item":    createdKey,
+		"key":     key,
+	})
+}
+
+// HandleGetAPIKeys lists the authenticated user's active API keys, without the keys themselves.
+func HandleGetAPIKeys(context *gin.Context) {
+	responseCode := http.StatusOK
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	keyList, retrievalError := repository.RetrieveAPIKeys(context.Request.Context(), database.DbConnection, userRecord.ID)
+
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"items": keyList,
+	})
+}
+
+// HandleRevokeAPIKey revokes one of the authenticated user's API keys.
+func HandleRevokeAPIKey(context *gin.Context) {
+	responseCode := http.StatusOK
+	keyID, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Invalid API key ID",
+		})
+		return
+	}
+
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	revokeError := repository.RevokeAPIKey(context.Request.Context(), database.DbConnection, userRecord.ID, keyID)
+
+	if revokeError.Message != "" {
+		responseCode = revokeError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": revokeError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "API key revoked successfully",
+	})
+}
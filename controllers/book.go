@@ -3,6 +3,7 @@ package controllers
 import (
 	"net/http"
 	"sb-go-readrate-nabiel/database"
+	"sb-go-readrate-nabiel/metrics"
 	"sb-go-readrate-nabiel/repository"
 	"sb-go-readrate-nabiel/structs"
 	"strconv"
@@ -12,7 +13,7 @@ import (
 
 func HandleFindBooks(context *gin.Context) {
 	responseCode := http.StatusOK
-	bookList, retrievalError := repository.RetrieveBooks(database.DbConnection)
+	bookList, retrievalError := repository.RetrieveBooks(context.Request.Context(), database.DbConnection)
 
 	if retrievalError.Message != "" {
 		responseCode = retrievalError.Status
@@ -38,7 +39,7 @@ func HandleFindBook(context *gin.Context) {
 		return
 	}
 
-	singleBook, retrievalError := repository.FindSingleBook(database.DbConnection, bookID)
+	singleBook, retrievalError := repository.FindSingleBook(context.Request.Context(), database.DbConnection, bookID)
 
 	if retrievalError.Message != "" {
 		responseCode = retrievalError.Status
@@ -98,7 +99,7 @@ func HandleCreateBook(context *gin.Context) {
 	newBook.CreatedBy = userRecord.ID // Changed to int
 	newBook.ModifiedBy = userRecord.ID // Changed to int
 
-	creationError := repository.StoreBook(database.DbConnection, newBook)
+	creationError := repository.StoreBook(context.Request.Context(), database.DbConnection, newBook)
 
 	if creationError.Message != "" {
 		responseCode = creationError.Status
@@ -107,6 +108,7 @@ func HandleCreateBook(context *gin.Context) {
 		})
 		return
 	}
+	metrics.BooksCreated.Inc()
 
 	context.JSON(responseCode, gin.H{
 		"status": "success",
@@ -168,7 +170,7 @@ func HandleUpdateBook(context *gin.Context) {
 	updatedBook.ID = bookID
 	updatedBook.ModifiedBy = userRecord.ID // Changed to int
 
-	updateError := repository.UpdateExistingBook(database.DbConnection, updatedBook)
+	updateError := repository.UpdateExistingBook(context.Request.Context(), database.DbConnection, updatedBook)
 
 	if updateError.Message != "" {
 		responseCode = updateError.Status
@@ -195,7 +197,7 @@ func HandleDeleteBook(context *gin.Context) {
 		return
 	}
 
-	deletionError := repository.EraseBook(database.DbConnection, bookID)
+	deletionError := repository.EraseBook(context.Request.Context(), database.DbConnection, bookID)
 
 	if deletionError.Message != "" {
 		responseCode = deletionError.Status
@@ -12,7 +12,7 @@ import (
 
 func HandleFindCategories(context *gin.Context) {
 	responseCode := http.StatusOK
-	categoryList, retrievalError := repository.RetrieveCategories(database.DbConnection)
+	categoryList, retrievalError := repository.RetrieveCategories(context.Request.Context(), database.DbConnection)
 
 	if retrievalError.Message != "" {
 		responseCode = retrievalError.Status
@@ -58,7 +58,7 @@ func HandleCreateCategory(context *gin.Context) {
 	newCategory.CreatedBy = userRecord.ID   // Changed to int
 	newCategory.ModifiedBy = userRecord.ID // Changed to int
 
-	creationError := repository.StoreCategory(database.DbConnection, newCategory)
+	creationError := repository.StoreCategory(context.Request.Context(), database.DbConnection, newCategory)
 
 	if creationError.Message != "" {
 		responseCode = creationError.Status
@@ -117,7 +117,7 @@ func HandleUpdateCategory(context *gin.Context) {
 	// updatedCategory.CreatedBy = userRecord.ID
 	updatedCategory.ModifiedBy = userRecord.ID // Changed to int
 
-	updateError := repository.UpdateCategory(database.DbConnection, updatedCategory)
+	updateError := repository.UpdateCategory(context.Request.Context(), database.DbConnection, updatedCategory)
 
 	if updateError.Message != "" {
 		responseCode = updateError.Status
@@ -144,7 +144,7 @@ func HandleDeleteCategory(context *gin.Context) {
 		return
 	}
 
-	deletionError := repository.EraseCategory(database.DbConnection, categoryID)
+	deletionError := repository.EraseCategory(context.Request.Context(), database.DbConnection, categoryID)
 
 	if deletionError.Message != "" {
 		responseCode = deletionError.Status
@@ -171,7 +171,7 @@ func HandleFindCategory(context *gin.Context) {
 		return
 	}
 
-	singleCategory, retrievalError := repository.RetrieveCategory(database.DbConnection, categoryID)
+	singleCategory, retrievalError := repository.RetrieveCategory(context.Request.Context(), database.DbConnection, categoryID)
 
 	if retrievalError.Message != "" {
 		responseCode = retrievalError.Status
@@ -197,7 +197,7 @@ func HandleFindBooksByCategory(context *gin.Context) {
 		return
 	}
 
-	booksInCategory, retrievalError := repository.RetrieveBooksForCategory(database.DbConnection, categoryID)
+	booksInCategory, retrievalError := repository.RetrieveBooksForCategory(context.Request.Context(), database.DbConnection, categoryID)
 
 	if retrievalError.Message != "" {
 		responseCode = retrievalError.Status
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"net/http"
+	"sb-go-readrate-nabiel/charts"
+	"sb-go-readrate-nabiel/database"
+	"sb-go-readrate-nabiel/repository"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultChartLimit = 10
+	maxChartLimit     = 100
+)
+
+// HandleGetTopRatedChart returns the books with the highest Bayesian weighted rating.
+func HandleGetTopRatedChart(context *gin.Context) {
+	respondWithChart(context, charts.TopRated, 0)
+}
+
+// HandleGetTrendingChart returns the books with the highest review velocity in the trending window.
+func HandleGetTrendingChart(context *gin.Context) {
+	respondWithChart(context, charts.Trending, 0)
+}
+
+// HandleGetCategoryTopRatedChart returns the top-rated chart for a single category.
+func HandleGetCategoryTopRatedChart(context *gin.Context) {
+	categoryID, err := strconv.Atoi(context.Param("id"))
+	if err != nil || categoryID < 1 {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"detail": "Invalid category ID",
+		})
+		return
+	}
+	respondWithChart(context, charts.TopRated, categoryID)
+}
+
+// HandleGetCategoryTrendingChart returns the trending chart for a single category.
+func HandleGetCategoryTrendingChart(context *gin.Context) {
+	categoryID, err := strconv.Atoi(context.Param("id"))
+	if err != nil || categoryID < 1 {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"detail": "Invalid category ID",
+		})
+		return
+	}
+	respondWithChart(context, charts.Trending, categoryID)
+}
+
+func respondWithChart(context *gin.Context, chart string, categoryID int) {
+	responseCode := http.StatusOK
+	limit, err := strconv.Atoi(context.DefaultQuery("limit", strconv.Itoa(defaultChartLimit)))
+	if err != nil || limit < 1 || limit > maxChartLimit {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Limit must be a number between 1 and " + strconv.Itoa(maxChartLimit),
+		})
+		return
+	}
+
+	if categoryID != 0 {
+		if _, retrievalError := repository.RetrieveCategory(context.Request.Context(), database.DbConnection, categoryID); retrievalError.Message != "" {
+			responseCode = retrievalError.Status
+			context.JSON(responseCode, gin.H{
+				"detail": retrievalError.Message,
+			})
+			return
+		}
+	}
+
+	chartEntries, retrievalError := repository.RetrieveChart(context.Request.Context(), database.DbConnection, chart, categoryID, limit)
+
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"items": chartEntries,
+	})
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sb-go-readrate-nabiel/buildinfo"
+	"sb-go-readrate-nabiel/database"
+	"sb-go-readrate-nabiel/health"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadinessChecks are the dependencies /readyz verifies.
+var ReadinessChecks []health.Check
+
+// ReadinessTimeout bounds how long /readyz waits for its checks.
+var ReadinessTimeout = 2 * time.Second
+
+// HandleHealthz reports that the process is alive. It checks no dependencies, so an orchestrator
+// only restarts the service when it is truly stuck.
+func HandleHealthz(context *gin.Context) {
+	context.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+	})
+}
+
+// HandleReadyz reports whether the service can handle requests: the database answers, its
+// migrations are current and the storage backends are usable. It responds 503 otherwise.
+func HandleReadyz(ginContext *gin.Context) {
+	responseCode := http.StatusOK
+	checkContext, cancel := context.WithTimeout(ginContext.Request.Context(), ReadinessTimeout)
+	defer cancel()
+
+	results, ready := health.Run(checkContext, ReadinessChecks)
+
+	status := "ready"
+	if !ready {
+		responseCode = http.StatusServiceUnavailable
+		status = "not ready"
+	}
+
+	ginContext.JSON(responseCode, gin.H{
+		"status": status,
+		"checks": results,
+	})
+}
+
+// HandleVersion returns the build commit and time along with the newest applied migration.
+func HandleVersion(ginContext *gin.Context) {
+	responseCode := http.StatusOK
+	queryContext, cancel := context.WithTimeout(ginContext.Request.Context(), ReadinessTimeout)
+	defer cancel()
+
+	latestMigration, _, err := database.MigrationStatus(queryContext, database.DbConnection)
+	if err != nil {
+		responseCode = http.StatusInternalServerError
+		ginContext.JSON(responseCode, gin.H{
+			"detail": "Failed to read the applied migrations: " + err.Error(),
+		})
+		return
+	}
+
+	ginContext.JSON(responseCode, gin.H{
+		"build":     buildinfo.Get(),
+		"migration": latestMigration,
+	})
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"net/http"
+	"sb-go-readrate-nabiel/database"
+	"sb-go-readrate-nabiel/metrics"
+	"sb-go-readrate-nabiel/repository"
+	"sb-go-readrate-nabiel/structs"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportHideThreshold is the number of open reports after which a review is hidden automatically.
+// A value of 0 disables automatic hiding.
+var ReportHideThreshold = 3
+
+// HandleReportReview allows an authenticated user to report someone else's review.
+func HandleReportReview(context *gin.Context) {
+	responseCode := http.StatusOK
+	reviewID, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Invalid review ID",
+		})
+		return
+	}
+
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	var newReport structs.ReviewReport
+	if bindError := context.ShouldBindJSON(&newReport); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	if strings.TrimSpace(newReport.Reason) == "" {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Report reason cannot be empty",
+		})
+		return
+	}
+
+	existingReview, retrieveErr := repository.FindSingleReview(context.Request.Context(), database.DbConnection, reviewID)
+	if retrieveErr.Message != "" {
+		responseCode = retrieveErr.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrieveErr.Message,
+		})
+		return
+	}
+
+	if existingReview.UserID == userRecord.ID {
+		responseCode = http.StatusForbidden
+		context.JSON(responseCode, gin.H{
+			"detail": "You cannot report your own review",
+		})
+		return
+	}
+
+	newReport.ReviewID = reviewID
+	newReport.UserID = userRecord.ID
+	newReport.CreatedBy = userRecord.ID
+	newReport.ModifiedBy = userRecord.ID
+
+	creationError := repository.StoreReviewReport(context.Request.Context(), database.DbConnection, newReport, ReportHideThreshold)
+
+	if creationError.Message != "" {
+		responseCode = creationError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": creationError.Message,
+		})
+		return
+	}
+	metrics.ReviewsReported.Inc()
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Review reported successfully",
+	})
+}
+
+// HandleGetModerationQueue lists reported reviews with their open reports, most reported first.
+func HandleGetModerationQueue(context *gin.Context) {
+	responseCode := http.StatusOK
+	page, pageSize, ok := parsePagination(context)
+	if !ok {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Page must be a positive number and page_size must be between 1 and " + strconv.Itoa(maxPageSize),
+		})
+		return
+	}
+
+	queueItems, total, retrievalError := repository.RetrieveModerationQueue(context.Request.Context(), database.DbConnection, page, pageSize)
+
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	// Moderators always see the raw text
+	for i := range queueItems {
+		presentReview(&queueItems[i].Review, true)
+	}
+
+	context.JSON(responseCode, gin.H{
+		"items":     queueItems,
+		"page":      page,
+		"page_size": pageSize,
+		"total":     total,
+	})
+}
+
+// HandleApproveReview makes a reported review visible again and resolves its reports.
+func HandleApproveReview(context *gin.Context) {
+	moderateReview(context, false, "Review approved successfully")
+}
+
+// HandleHideReview hides a review from public listings and resolves its reports.
+func HandleHideReview(context *gin.Context) {
+	moderateReview(context, true, "Review hidden successfully")
+}
+
+// HandleModeratorDeleteReview lets a moderator delete any review.
+func HandleModeratorDeleteReview(context *gin.Context) {
+	responseCode := http.StatusOK
+	reviewID, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Invalid review ID",
+		})
+		return
+	}
+
+	deletionError := repository.EraseReview(context.Request.Context(), database.DbConnection, reviewID)
+
+	if deletionError.Message != "" {
+		responseCode = deletionError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": deletionError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Review deleted successfully",
+	})
+}
+
+func moderateReview(context *gin.Context, hidden bool, successMessage string) {
+	responseCode := http.StatusOK
+	reviewID, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Invalid review ID",
+		})
+		return
+	}
+
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	moderationError := repository.ModerateReview(context.Request.Context(), database.DbConnection, reviewID, hidden, userRecord.ID)
+
+	if moderationError.Message != "" {
+		responseCode = moderationError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": moderationError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": successMessage,
+	})
+}
@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// parsePagination reads the "page" and "page_size" query parameters. ok is false when either
+// value is not a positive number or page_size exceeds maxPageSize.
+func parsePagination(context *gin.Context) (page int, pageSize int, ok bool) {
+	page, errPage := strconv.Atoi(context.DefaultQuery("page", "1"))
+	pageSize, errSize := strconv.Atoi(context.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if errPage != nil || errSize != nil || page < 1 || pageSize < 1 || pageSize > maxPageSize {
+		return 0, 0, false
+	}
+	return page, pageSize, true
+}
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"bytes"
+	"net/http"
+	"sb-go-readrate-nabiel/database"
+	"sb-go-readrate-nabiel/dataexport"
+	"sb-go-readrate-nabiel/repository"
+	"sb-go-readrate-nabiel/structs"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountDeletionGracePeriod is how long a deletion request can still be cancelled before the
+// account is erased.
+var AccountDeletionGracePeriod = 30 * 24 * time.Hour
+
+// HandleExportMe returns a ZIP archive with the authenticated user's profile, reviews and
+// activity as JSON and CSV.
+func HandleExportMe(context *gin.Context) {
+	responseCode := http.StatusOK
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	profile, retrievalError := repository.FindUserByID(context.Request.Context(), database.DbConnection, userRecord.ID)
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	reviewList, retrievalError := repository.RetrieveAllReviewsByUser(context.Request.Context(), database.DbConnection, userRecord.ID)
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	activityList, retrievalError := repository.RetrieveUserActivity(context.Request.Context(), database.DbConnection, userRecord.ID)
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	exportedAt := time.Now().UTC()
+	var archive bytes.Buffer
+	err := dataexport.Write(&archive, dataexport.Archive{
+		Profile:    profile,
+		Reviews:    reviewList,
+		Activity:   activityList,
+		ExportedAt: exportedAt,
+	})
+	if err != nil {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to build data export: " + err.Error(),
+		})
+		return
+	}
+
+	fileName := "readrate-export-" + strconv.Itoa(profile.ID) + "-" + exportedAt.Format("20060102") + ".zip"
+	context.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	context.Data(responseCode, "application/zip", archive.Bytes())
+}
+
+// HandleDeleteMe schedules the authenticated user's account for erasure after the grace period.
+// The user chooses whether their reviews are deleted or anonymized.
+func HandleDeleteMe(context *gin.Context) {
+	responseCode := http.StatusOK
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	var deletionRequest structs.AccountDeletionRequest
+	if bindError := context.ShouldBindJSON(&deletionRequest); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	if deletionRequest.Reviews != structs.ReviewsDelete && deletionRequest.Reviews != structs.ReviewsAnonymize {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": `Reviews must be "delete" or "anonymize"`,
+		})
+		return
+	}
+
+	erasedAt := time.Now().Add(AccountDeletionGracePeriod)
+	scheduleError := repository.ScheduleAccountDeletion(context.Request.Context(), database.DbConnection, userRecord.ID, deletionRequest.Reviews, erasedAt)
+
+	if scheduleError.Message != "" {
+		responseCode = scheduleError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": scheduleError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":                 "success",
+		"message":                "Account scheduled for deletion; cancel through POST /me/cancel-deletion before then",
+		"deletion_scheduled_for": erasedAt,
+	})
+}
+
+// HandleCancelAccountDeletion withdraws the authenticated user's pending deletion request.
+func HandleCancelAccountDeletion(context *gin.Context) {
+	responseCode := http.StatusOK
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	cancelError := repository.CancelAccountDeletion(context.Request.Context(), database.DbConnection, userRecord.ID)
+
+	if cancelError.Message != "" {
+		responseCode = cancelError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": cancelError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Account deletion cancelled",
+	})
+}
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"net/http"
+	"sb-go-readrate-nabiel/database"
+	"sb-go-readrate-nabiel/repository"
+	"sb-go-readrate-nabiel/structs"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleFindRatingAspects lists every rating aspect.
+func HandleFindRatingAspects(context *gin.Context) {
+	responseCode := http.StatusOK
+	aspectList, retrievalError := repository.RetrieveRatingAspects(context.Request.Context(), database.DbConnection)
+
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"items": aspectList,
+	})
+}
+
+// HandleCreateRatingAspect adds a new rating aspect.
+func HandleCreateRatingAspect(context *gin.Context) {
+	responseCode := http.StatusOK
+	var newAspect structs.RatingAspect
+	if bindError := context.ShouldBindJSON(&newAspect); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "User not authenticated",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	newAspect.CreatedBy = userRecord.ID
+	newAspect.ModifiedBy = userRecord.ID
+
+	creationError := repository.StoreRatingAspect(context.Request.Context(), database.DbConnection, newAspect)
+
+	if creationError.Message != "" {
+		responseCode = creationError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": creationError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Rating aspect created successfully",
+	})
+}
+
+// HandleFindCategoryAspects lists the aspects reviews of books in a category can rate.
+func HandleFindCategoryAspects(context *gin.Context) {
+	responseCode := http.StatusOK
+	categoryID, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Invalid category ID",
+		})
+		return
+	}
+
+	if _, retrievalError := repository.RetrieveCategory(context.Request.Context(), database.DbConnection, categoryID); retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	aspectList, retrievalError := repository.RetrieveAspectsForCategory(context.Request.Context(), database.DbConnection, categoryID)
+
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"items": aspectList,
+	})
+}
+
+// HandleUpdateCategoryAspects replaces the aspect set of a category. An empty list makes the
+// category fall back to the default aspects.
+func HandleUpdateCategoryAspects(context *gin.Context) {
+	responseCode := http.StatusOK
+	categoryID, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Invalid category ID",
+		})
+		return
+	}
+
+	var aspectSet struct {
+		Aspects []string `json:"aspects"`
+	}
+	if bindError := context.ShouldBindJSON(&aspectSet); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	updateError := repository.ReplaceCategoryAspects(context.Request.Context(), database.DbConnection, categoryID, aspectSet.Aspects)
+
+	if updateError.Message != "" {
+		responseCode = updateError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": updateError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Category rating aspects updated successfully",
+	})
+}
+
+// HandleGetBookRatingSummary returns a book's overall rating alongside the average of each aspect.
+func HandleGetBookRatingSummary(context *gin.Context) {
+	responseCode := http.StatusOK
+	bookID, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Invalid book ID",
+		})
+		return
+	}
+
+	ratingSummary, retrievalError := repository.RetrieveBookRatingSummary(context.Request.Context(), database.DbConnection, bookID)
+
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"item": ratingSummary,
+	})
+}
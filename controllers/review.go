@@ -3,13 +3,105 @@ package controllers
 import (
 	"net/http"
 	"sb-go-readrate-nabiel/database"
+	"sb-go-readrate-nabiel/metrics"
+	"sb-go-readrate-nabiel/rating"
 	"sb-go-readrate-nabiel/repository"
+	"sb-go-readrate-nabiel/reviewfilter"
+	"sb-go-readrate-nabiel/spoiler"
 	"sb-go-readrate-nabiel/structs"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+// RatingScale is the accepted range and step of overall and aspect ratings.
+var RatingScale = rating.DefaultScale()
+
+// ReviewFilter checks review comments on create and update. A nil filter accepts everything.
+var ReviewFilter *reviewfilter.Pipeline
+
+// presentReview fills in CommentSafe. Unless revealSpoilers is set, Comment is replaced with the
+// safe version as well, so spoilers never reach clients that did not ask for them.
+func presentReview(review *structs.Review, revealSpoilers bool) {
+	review.CommentSafe = safeComment(review.Comment, review.ContainsSpoilers)
+	if !revealSpoilers {
+		review.Comment = review.CommentSafe
+	}
+}
+
+// safeComment hides the whole comment for spoiler reviews and redacts inline spoilers otherwise.
+func safeComment(comment string, containsSpoilers bool) string {
+	if containsSpoilers {
+		return spoiler.Placeholder
+	}
+	return spoiler.Redact(comment)
+}
+
+// validateAspectRatings checks that every sub-rating targets an aspect of the book's category and
+// fits RatingScale. It writes the error response itself and returns false on failure.
+func validateAspectRatings(context *gin.Context, bookID int, aspectRatings map[string]float64) bool {
+	if len(aspectRatings) == 0 {
+		return true
+	}
+
+	allowedAspects, retrievalError := repository.RetrieveAspectsForBook(context.Request.Context(), database.DbConnection, bookID)
+	if retrievalError.Message != "" {
+		context.JSON(retrievalError.Status, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return false
+	}
+
+	allowed := map[string]bool{}
+	for _, aspect := range allowedAspects {
+		allowed[aspect.Code] = true
+	}
+
+	for aspectCode, aspectRating := range aspectRatings {
+		if !allowed[aspectCode] {
+			context.JSON(http.StatusBadRequest, gin.H{
+				"detail": "Aspect " + strconv.Quote(aspectCode) + " cannot be rated for this book",
+			})
+			return false
+		}
+		if scaleError := RatingScale.Validate(aspectRating); scaleError != nil {
+			context.JSON(http.StatusBadRequest, gin.H{
+				"detail": "Aspect rating for " + strconv.Quote(aspectCode) + " " + scaleError.Error(),
+			})
+			return false
+		}
+	}
+	return true
+}
+
+// parseRevealSpoilers reads the "reveal_spoilers" query parameter (false by default).
+func parseRevealSpoilers(context *gin.Context) (revealSpoilers bool, ok bool) {
+	revealSpoilers, err := strconv.ParseBool(context.DefaultQuery("reveal_spoilers", "false"))
+	return revealSpoilers, err == nil
+}
+
+// filterReviewComment runs comment through ReviewFilter, comparing its masked form against every
+// review except excludeReviewID for duplicates. It writes the error response itself and returns false when the
+// review must not be stored.
+func filterReviewComment(context *gin.Context, comment string, excludeReviewID int) (outcome reviewfilter.Outcome, accepted bool) {
+	duplicateCount, countError := repository.CountDuplicateReviews(context.Request.Context(), database.DbConnection, ReviewFilter.Mask(comment), excludeReviewID)
+	if countError.Message != "" {
+		context.JSON(countError.Status, gin.H{
+			"detail": countError.Message,
+		})
+		return outcome, false
+	}
+
+	outcome = ReviewFilter.Run(reviewfilter.Input{Text: comment, DuplicateCount: duplicateCount})
+	if outcome.Rejected {
+		context.JSON(http.StatusUnprocessableEntity, gin.H{
+			"detail": "Review comment was rejected: " + outcome.Summary(),
+		})
+		return outcome, false
+	}
+	return outcome, true
+}
+
 // HandleCreateReview allows an authenticated user to create a review for a specific book.
 func HandleCreateReview(context *gin.Context) {
 	responseCode := http.StatusOK
@@ -49,20 +141,31 @@ func HandleCreateReview(context *gin.Context) {
 	}
 
 	// Basic validation for rating
-	if newReview.Rating < 1 || newReview.Rating > 5 {
+	if scaleError := RatingScale.Validate(newReview.Rating); scaleError != nil {
 		responseCode = http.StatusBadRequest
 		context.JSON(responseCode, gin.H{
-			"detail": "Rating must be between 1 and 5",
+			"detail": "Rating " + scaleError.Error(),
 		})
 		return
 	}
 
+	if !validateAspectRatings(context, bookID, newReview.AspectRatings) {
+		return
+	}
+
+	filterOutcome, accepted := filterReviewComment(context, newReview.Comment, 0)
+	if !accepted {
+		return
+	}
+
+	newReview.Comment = filterOutcome.Text
 	newReview.BookID = bookID
 	newReview.UserID = userRecord.ID
 	newReview.CreatedBy = userRecord.ID
 	newReview.ModifiedBy = userRecord.ID
 
-	creationError := repository.StoreReview(database.DbConnection, newReview)
+	// Flagged reviews are sent to the moderation queue along with the write
+	_, creationError := repository.StoreReview(context.Request.Context(), database.DbConnection, newReview, filterOutcome.Flags)
 
 	if creationError.Message != "" {
 		responseCode = creationError.Status
@@ -71,6 +174,7 @@ func HandleCreateReview(context *gin.Context) {
 		})
 		return
 	}
+	metrics.ReviewsCreated.Inc()
 
 	context.JSON(responseCode, gin.H{
 		"status":  "success",
@@ -78,7 +182,9 @@ func HandleCreateReview(context *gin.Context) {
 	})
 }
 
-// HandleGetReviewsForBook retrieves all reviews for a specified book.
+// HandleGetReviewsForBook retrieves all reviews for a specified book, ordered by the "sort" query
+// parameter (helpful, newest, oldest, rating_high or rating_low; newest by default). Spoilers are
+// redacted unless "reveal_spoilers=true" is given.
 func HandleGetReviewsForBook(context *gin.Context) {
 	responseCode := http.StatusOK
 	bookID, err := strconv.Atoi(context.Param("id")) // Note: param is "id" in main.go route for this
@@ -90,7 +196,18 @@ func HandleGetReviewsForBook(context *gin.Context) {
 		return
 	}
 
-	reviewList, retrievalError := repository.RetrieveReviewsForBook(database.DbConnection, bookID)
+	revealSpoilers, ok := parseRevealSpoilers(context)
+	if !ok {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "reveal_spoilers must be true or false",
+		})
+		return
+	}
+
+	sortOrder := context.DefaultQuery("sort", "newest")
+
+	reviewList, retrievalError := repository.RetrieveReviewsForBook(context.Request.Context(), database.DbConnection, bookID, sortOrder)
 
 	if retrievalError.Message != "" {
 		responseCode = retrievalError.Status
@@ -100,15 +217,21 @@ func HandleGetReviewsForBook(context *gin.Context) {
 		return
 	}
 
+	for i := range reviewList {
+		presentReview(&reviewList[i], revealSpoilers)
+	}
+
 	context.JSON(responseCode, gin.H{
 		"items": reviewList,
 	})
 }
 
-// HandleGetReviewsByUser retrieves all reviews written by a specified user.
+// HandleGetReviewsByUser retrieves all reviews written by a specified user. Users may always list
+// their own reviews; anyone else's are only listed when the author's profile is public and shows
+// reviews. Spoilers are redacted unless "reveal_spoilers=true" is given.
 func HandleGetReviewsByUser(context *gin.Context) {
 	responseCode := http.StatusOK
-	userID, err := strconv.Atoi(context.Param("user_id"))
+	userID, err := strconv.Atoi(context.Param("id"))
 	if err != nil {
 		responseCode = http.StatusBadRequest
 		context.JSON(responseCode, gin.H{
@@ -117,11 +240,52 @@ func HandleGetReviewsByUser(context *gin.Context) {
 		return
 	}
 
-	// Optional: Add authorization here if only authenticated user can see their own reviews,
-	// or if only admins can see reviews of any user.
-	// For now, assuming anyone can see reviews of any user by ID if they have the ID.
+	revealSpoilers, ok := parseRevealSpoilers(context)
+	if !ok {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "reveal_spoilers must be true or false",
+		})
+		return
+	}
+
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	// Other users' reviews are only listed when their profile shows them
+	if userRecord.ID != userID {
+		author, authorError := repository.FindUserByID(context.Request.Context(), database.DbConnection, userID)
+		if authorError.Message != "" {
+			responseCode = authorError.Status
+			context.JSON(responseCode, gin.H{
+				"detail": authorError.Message,
+			})
+			return
+		}
+		if !author.ProfilePublic || !author.ShowReviews {
+			responseCode = http.StatusForbidden
+			context.JSON(responseCode, gin.H{
+				"detail": "This user's reviews are private",
+			})
+			return
+		}
+	}
 
-	reviewList, retrievalError := repository.RetrieveReviewsByUser(database.DbConnection, userID)
+	reviewList, retrievalError := repository.RetrieveReviewsByUser(context.Request.Context(), database.DbConnection, userID)
 
 	if retrievalError.Message != "" {
 		responseCode = retrievalError.Status
@@ -131,6 +295,10 @@ func HandleGetReviewsByUser(context *gin.Context) {
 		return
 	}
 
+	for i := range reviewList {
+		presentReview(&reviewList[i], revealSpoilers)
+	}
+
 	context.JSON(responseCode, gin.H{
 		"items": reviewList,
 	})
@@ -175,16 +343,16 @@ func HandleUpdateReview(context *gin.Context) {
 	}
 
 	// Basic validation for rating
-	if updatedReview.Rating < 1 || updatedReview.Rating > 5 {
+	if scaleError := RatingScale.Validate(updatedReview.Rating); scaleError != nil {
 		responseCode = http.StatusBadRequest
 		context.JSON(responseCode, gin.H{
-			"detail": "Rating must be between 1 and 5",
+			"detail": "Rating " + scaleError.Error(),
 		})
 		return
 	}
 
-	// Retrieve the existing review to check ownership
-	existingReview, retrieveErr := repository.FindSingleReview(database.DbConnection, reviewID)
+	// Retrieve the existing review for its book, whose category decides the valid aspects
+	existingReview, retrieveErr := repository.FindSingleReview(context.Request.Context(), database.DbConnection, reviewID)
 	if retrieveErr.Message != "" {
 		responseCode = retrieveErr.Status
 		context.JSON(responseCode, gin.H{
@@ -193,19 +361,22 @@ func HandleUpdateReview(context *gin.Context) {
 		return
 	}
 
-	// Authorization: Check if the current user is the owner of the review
-	if existingReview.UserID != userRecord.ID {
-		responseCode = http.StatusForbidden // 403 Forbidden
-		context.JSON(responseCode, gin.H{
-			"detail": "You are not authorized to update this review",
-		})
+	if !validateAspectRatings(context, existingReview.BookID, updatedReview.AspectRatings) {
+		return
+	}
+
+	filterOutcome, accepted := filterReviewComment(context, updatedReview.Comment, reviewID)
+	if !accepted {
 		return
 	}
 
+	updatedReview.Comment = filterOutcome.Text
 	updatedReview.ID = reviewID
+	updatedReview.UserID = userRecord.ID // Only the author's review is updated; ownership is checked with the write
 	updatedReview.ModifiedBy = userRecord.ID
 
-	updateError := repository.UpdateExistingReview(database.DbConnection, updatedReview)
+	// Flagged reviews are sent to the moderation queue along with the write
+	updateError := repository.UpdateExistingReview(context.Request.Context(), database.DbConnection, updatedReview, filterOutcome.Flags)
 
 	if updateError.Message != "" {
 		responseCode = updateError.Status
@@ -250,8 +421,46 @@ func HandleDeleteReview(context *gin.Context) {
 		return
 	}
 
-	// Retrieve the existing review to check ownership
-	existingReview, retrieveErr := repository.FindSingleReview(database.DbConnection, reviewID)
+	// Ownership is checked by the delete itself
+	deletionError := repository.EraseOwnReview(context.Request.Context(), database.DbConnection, reviewID, userRecord.ID)
+
+	if deletionError.Message != "" {
+		responseCode = deletionError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": deletionError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Review deleted successfully",
+	})
+}
+
+// HandleGetReviewRevisions lists the earlier versions of a review with their ratings and timestamps.
+// Spoilers are redacted unless "reveal_spoilers=true" is given.
+func HandleGetReviewRevisions(context *gin.Context) {
+	responseCode := http.StatusOK
+	reviewID, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Invalid review ID",
+		})
+		return
+	}
+
+	revealSpoilers, ok := parseRevealSpoilers(context)
+	if !ok {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "reveal_spoilers must be true or false",
+		})
+		return
+	}
+
+	existingReview, retrieveErr := repository.FindSingleReview(context.Request.Context(), database.DbConnection, reviewID)
 	if retrieveErr.Message != "" {
 		responseCode = retrieveErr.Status
 		context.JSON(responseCode, gin.H{
@@ -260,27 +469,33 @@ func HandleDeleteReview(context *gin.Context) {
 		return
 	}
 
-	// Authorization: Check if the current user is the owner of the review
-	if existingReview.UserID != userRecord.ID {
-		responseCode = http.StatusForbidden // 403 Forbidden
+	// Hidden reviews are not public, and neither is their history
+	if existingReview.Hidden {
+		responseCode = http.StatusNotFound
 		context.JSON(responseCode, gin.H{
-			"detail": "You are not authorized to delete this review",
+			"detail": "review with identifier " + strconv.Itoa(reviewID) + " not found",
 		})
 		return
 	}
 
-	deletionError := repository.EraseReview(database.DbConnection, reviewID)
+	revisionList, retrievalError := repository.RetrieveReviewRevisions(context.Request.Context(), database.DbConnection, reviewID)
 
-	if deletionError.Message != "" {
-		responseCode = deletionError.Status
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
 		context.JSON(responseCode, gin.H{
-			"detail": deletionError.Message,
+			"detail": retrievalError.Message,
 		})
 		return
 	}
 
+	for i := range revisionList {
+		revisionList[i].CommentSafe = safeComment(revisionList[i].Comment, revisionList[i].ContainsSpoilers)
+		if !revealSpoilers {
+			revisionList[i].Comment = revisionList[i].CommentSafe
+		}
+	}
+
 	context.JSON(responseCode, gin.H{
-		"status":  "success",
-		"message": "Review deleted successfully",
+		"items": revisionList,
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"net/http"
+	"sb-go-readrate-nabiel/database"
+	"sb-go-readrate-nabiel/metrics"
+	"sb-go-readrate-nabiel/repository"
+	"sb-go-readrate-nabiel/structs"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetReviewComments retrieves a page of comments (with their replies) for a review.
+func HandleGetReviewComments(context *gin.Context) {
+	responseCode := http.StatusOK
+	reviewID, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Invalid review ID",
+		})
+		return
+	}
+
+	page, pageSize, ok := parsePagination(context)
+	if !ok {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Page must be a positive number and page_size must be between 1 and " + strconv.Itoa(maxPageSize),
+		})
+		return
+	}
+
+	commentList, total, retrievalError := repository.RetrieveReviewComments(context.Request.Context(), database.DbConnection, reviewID, page, pageSize)
+
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"items":     commentList,
+		"page":      page,
+		"page_size": pageSize,
+		"total":     total,
+	})
+}
+
+// HandleCreateReviewComment allows an authenticated user to comment on a review or reply to a
+// top-level comment.
+func HandleCreateReviewComment(context *gin.Context) {
+	responseCode := http.StatusOK
+	reviewID, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Invalid review ID",
+		})
+		return
+	}
+
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	var newComment structs.ReviewComment
+	if bindError := context.ShouldBindJSON(&newComment); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	if strings.TrimSpace(newComment.Body) == "" {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Comment body cannot be empty",
+		})
+		return
+	}
+
+	newComment.ReviewID = reviewID
+	newComment.UserID = userRecord.ID
+	newComment.CreatedBy = userRecord.ID
+	newComment.ModifiedBy = userRecord.ID
+
+	creationError := repository.StoreReviewComment(context.Request.Context(), database.DbConnection, newComment)
+
+	if creationError.Message != "" {
+		responseCode = creationError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": creationError.Message,
+		})
+		return
+	}
+	metrics.CommentsCreated.Inc()
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Comment created successfully",
+	})
+}
+
+// HandleUpdateReviewComment allows an authenticated user to edit their own comment.
+func HandleUpdateReviewComment(context *gin.Context) {
+	responseCode := http.StatusOK
+	commentID, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Invalid comment ID",
+		})
+		return
+	}
+
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	var updatedComment structs.ReviewComment
+	if bindError := context.ShouldBindJSON(&updatedComment); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	if strings.TrimSpace(updatedComment.Body) == "" {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Comment body cannot be empty",
+		})
+		return
+	}
+
+	updatedComment.ID = commentID
+	updatedComment.UserID = userRecord.ID // Only the author's comment is updated; ownership is checked with the write
+	updatedComment.ModifiedBy = userRecord.ID
+
+	updateError := repository.UpdateExistingReviewComment(context.Request.Context(), database.DbConnection, updatedComment)
+
+	if updateError.Message != "" {
+		responseCode = updateError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": updateError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Comment updated successfully",
+	})
+}
+
+// HandleDeleteReviewComment allows an authenticated user to delete their own comment. Replies to
+// the comment are deleted with it.
+func HandleDeleteReviewComment(context *gin.Context) {
+	responseCode := http.StatusOK
+	commentID, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Invalid comment ID",
+		})
+		return
+	}
+
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	// Ownership is checked by the delete itself
+	deletionError := repository.EraseOwnReviewComment(context.Request.Context(), database.DbConnection, commentID, userRecord.ID)
+
+	if deletionError.Message != "" {
+		responseCode = deletionError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": deletionError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Comment deleted successfully",
+	})
+}
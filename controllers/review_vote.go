@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"net/http"
+	"sb-go-readrate-nabiel/database"
+	"sb-go-readrate-nabiel/repository"
+	"sb-go-readrate-nabiel/structs"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleVoteReview lets an authenticated user mark someone else's review as helpful or unhelpful.
+func HandleVoteReview(context *gin.Context) {
+	responseCode := http.StatusOK
+	reviewID, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Invalid review ID",
+		})
+		return
+	}
+
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	var newVote structs.ReviewVote
+	if bindError := context.ShouldBindJSON(&newVote); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	existingReview, retrieveErr := repository.FindSingleReview(context.Request.Context(), database.DbConnection, reviewID)
+	if retrieveErr.Message != "" {
+		responseCode = retrieveErr.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrieveErr.Message,
+		})
+		return
+	}
+
+	// Authors cannot vote on their own review
+	if existingReview.UserID == userRecord.ID {
+		responseCode = http.StatusForbidden
+		context.JSON(responseCode, gin.H{
+			"detail": "You cannot vote on your own review",
+		})
+		return
+	}
+
+	newVote.ReviewID = reviewID
+	newVote.UserID = userRecord.ID
+	newVote.CreatedBy = userRecord.ID
+	newVote.ModifiedBy = userRecord.ID
+
+	creationError := repository.StoreReviewVote(context.Request.Context(), database.DbConnection, newVote)
+
+	if creationError.Message != "" {
+		responseCode = creationError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": creationError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Vote recorded successfully",
+	})
+}
+
+// HandleDeleteReviewVote withdraws the authenticated user's vote on a review.
+func HandleDeleteReviewVote(context *gin.Context) {
+	responseCode := http.StatusOK
+	reviewID, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Invalid review ID",
+		})
+		return
+	}
+
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	deletionError := repository.EraseReviewVote(context.Request.Context(), database.DbConnection, reviewID, userRecord.ID)
+
+	if deletionError.Message != "" {
+		responseCode = deletionError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": deletionError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Vote removed successfully",
+	})
+}
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"net/http"
+	"sb-go-readrate-nabiel/database"
+	"sb-go-readrate-nabiel/repository"
+	"sb-go-readrate-nabiel/structs"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionTTL is how long a session token from POST /login stays valid.
+var SessionTTL = 24 * time.Hour
+
+// HandleLogin issues a session token to a user whose password and, if enabled, second factor were
+// checked by middleware.Login.
+func HandleLogin(context *gin.Context) {
+	responseCode := http.StatusOK
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	token, expiresAt, sessionError := repository.StoreSession(context.Request.Context(), database.DbConnection, userRecord.ID, SessionTTL)
+
+	if sessionError.Message != "" {
+		responseCode = sessionError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": sessionError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":     "success",
+		"token":      token,
+		"token_type": "Bearer",
+		"expires_at": expiresAt,
+	})
+}
+
+// HandleLogout ends the session whose token authenticated the request.
+func HandleLogout(context *gin.Context) {
+	responseCode := http.StatusOK
+	token := context.GetString("session_token")
+	if token == "" {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Only requests signed in with a session token can log out",
+		})
+		return
+	}
+
+	revokeError := repository.RevokeSession(context.Request.Context(), database.DbConnection, token)
+
+	if revokeError.Message != "" {
+		responseCode = revokeError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": revokeError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Logged out",
+	})
+}
@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"sb-go-readrate-nabiel/database"
+	"sb-go-readrate-nabiel/loginguard"
+	"sb-go-readrate-nabiel/repository"
+	"sb-go-readrate-nabiel/structs"
+	"sb-go-readrate-nabiel/totp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorIssuer is the name authenticator apps show next to the account.
+var TwoFactorIssuer = "ReadRate"
+
+// HandleEnrollTwoFactor starts two-factor enrollment by generating a TOTP secret. It has to be
+// confirmed with a code before it is required at login.
+func HandleEnrollTwoFactor(context *gin.Context) {
+	responseCode := http.StatusOK
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to generate two-factor secret: " + err.Error(),
+		})
+		return
+	}
+
+	enrollmentError := repository.StartTwoFactorEnrollment(context.Request.Context(), database.DbConnection, userRecord.ID, secret)
+
+	if enrollmentError.Message != "" {
+		responseCode = enrollmentError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": enrollmentError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"item": structs.TwoFactorEnrollment{
+			Secret: secret,
+			URI:    totp.URI(TwoFactorIssuer, userRecord.Username, secret),
+		},
+	})
+}
+
+// HandleConfirmTwoFactor enables two-factor authentication once the user proves their
+// authenticator works, and returns single-use recovery codes that are shown only this once.
+func HandleConfirmTwoFactor(context *gin.Context) {
+	responseCode := http.StatusOK
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	var confirmation structs.TwoFactorConfirmation
+	if bindError := context.ShouldBindJSON(&confirmation); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	secret, enabled, retrievalError := repository.RetrieveTwoFactorSecret(context.Request.Context(), database.DbConnection, userRecord.ID)
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+	if enabled {
+		responseCode = http.StatusConflict
+		context.JSON(responseCode, gin.H{
+			"detail": "Two-factor authentication is already enabled",
+		})
+		return
+	}
+	if secret == "" {
+		responseCode = http.StatusConflict
+		context.JSON(responseCode, gin.H{
+			"detail": "Start two-factor enrollment through POST /me/2fa first",
+		})
+		return
+	}
+
+	counter, valid := totp.Verify(secret, strings.TrimSpace(confirmation.Code), time.Now())
+	if !valid {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Incorrect two-factor code",
+		})
+		return
+	}
+
+	recoveryCodes, enableError := repository.EnableTwoFactor(context.Request.Context(), database.DbConnection, userRecord.ID, counter)
+
+	if enableError.Message != "" {
+		responseCode = enableError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": enableError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":         "success",
+		"message":        "Two-factor authentication enabled; store the recovery codes somewhere safe and sign in again through POST /login",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// HandleRegenerateRecoveryCodes replaces the authenticated user's recovery codes once they prove
+// they still hold a second factor.
+func HandleRegenerateRecoveryCodes(context *gin.Context) {
+	responseCode := http.StatusOK
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	if !verifyTwoFactorCode(context, userRecord) {
+		return
+	}
+
+	recoveryCodes, regenerateError := repository.RegenerateRecoveryCodes(context.Request.Context(), database.DbConnection, userRecord.ID)
+
+	if regenerateError.Message != "" {
+		responseCode = regenerateError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": regenerateError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":         "success",
+		"message":        "Recovery codes regenerated; the old codes no longer work",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// HandleDisableTwoFactor turns off two-factor authentication once the user proves they still hold a
+// second factor, unless the user's role requires it.
+func HandleDisableTwoFactor(context *gin.Context) {
+	responseCode := http.StatusOK
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	required, policyError := repository.RoleRequiresTwoFactor(context.Request.Context(), database.DbConnection, userRecord.Role)
+	if policyError.Message != "" {
+		responseCode = policyError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": policyError.Message,
+		})
+		return
+	}
+	if required {
+		responseCode = http.StatusForbidden
+		context.JSON(responseCode, gin.H{
+			"detail": "Your role requires two-factor authentication",
+		})
+		return
+	}
+
+	if !verifyTwoFactorCode(context, userRecord) {
+		return
+	}
+
+	disableError := repository.DisableTwoFactor(context.Request.Context(), database.DbConnection, userRecord.ID)
+
+	if disableError.Message != "" {
+		responseCode = disableError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": disableError.Message,
+		})
+		return
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// verifyTwoFactorCode checks the current TOTP code or unused recovery code in the request body, so a
+// stolen session alone cannot change the user's second factor. Wrong codes count as failed logins.
+// It answers the request itself and returns false when the code is missing or not accepted.
+func verifyTwoFactorCode(context *gin.Context, userRecord structs.User) bool {
+	var check structs.TwoFactorCheck
+	if bindError := context.ShouldBindJSON(&check); bindError != nil {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"detail": "A current two-factor code or an unused recovery code is required: " + bindError.Error(),
+		})
+		return false
+	}
+	code := strings.TrimSpace(check.Code)
+
+	var attempt *loginguard.Attempt
+	if LoginGuard != nil {
+		var retryAfter time.Duration
+		if attempt, retryAfter, _ = LoginGuard.Begin(userRecord.Username, context.ClientIP()); attempt == nil {
+			context.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			context.JSON(http.StatusTooManyRequests, gin.H{
+				"detail": "Too many failed attempts, try again later",
+			})
+			return false
+		}
+	}
+
+	secret, enabled, problem := repository.RetrieveTwoFactorSecret(context.Request.Context(), database.DbConnection, userRecord.ID)
+	if problem.Message == "" && !enabled {
+		attempt.Release()
+		context.JSON(http.StatusConflict, gin.H{
+			"detail": "Two-factor authentication is not enabled",
+		})
+		return false
+	}
+
+	var valid bool
+	if problem.Message == "" {
+		if counter, matched := totp.Verify(secret, code, time.Now()); matched {
+			valid, problem = repository.AcceptTwoFactorCode(context.Request.Context(), database.DbConnection, userRecord.ID, counter)
+		} else {
+			valid, problem = repository.ConsumeRecoveryCode(context.Request.Context(), database.DbConnection, userRecord.ID, code)
+		}
+	}
+	if problem.Message != "" {
+		attempt.Release()
+		context.JSON(problem.Status, gin.H{
+			"detail": problem.Message,
+		})
+		return false
+	}
+	if !valid {
+		attempt.Fail(context.Request.Context())
+		context.JSON(http.StatusForbidden, gin.H{
+			"detail": "Incorrect or already used two-factor code",
+		})
+		return false
+	}
+
+	attempt.Succeed()
+	return true
+}
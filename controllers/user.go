@@ -1,19 +1,29 @@
 package controllers
 
 import (
+	"log/slog"
 	"net/http"
+	"net/url"
+	"sb-go-readrate-nabiel/credentials"
 	"sb-go-readrate-nabiel/database"
+	"sb-go-readrate-nabiel/metrics"
 	"sb-go-readrate-nabiel/repository"
 	"sb-go-readrate-nabiel/structs"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 )
 
+// CredentialPolicy holds the username and password rules for registration and password changes.
+var CredentialPolicy = credentials.DefaultPolicy()
+
 // HandleRegisterUser handles user registration.
 func HandleRegisterUser(context *gin.Context) {
 	responseCode := http.StatusOK
-	var newUser structs.User
-	if bindError := context.ShouldBindJSON(&newUser); bindError != nil {
+	var registration structs.RegisterRequest
+	if bindError := context.ShouldBindJSON(&registration); bindError != nil {
 		responseCode = http.StatusBadRequest
 		context.JSON(responseCode, gin.H{
 			"detail": bindError.Error(),
@@ -21,28 +31,273 @@ func HandleRegisterUser(context *gin.Context) {
 		return
 	}
 
-	// Basic validation
-	if newUser.Username == "" || newUser.Password == "" {
+	newUser := structs.User{Username: registration.Username, Password: registration.Password, Email: strings.TrimSpace(registration.Email)}
+
+	// Validate every field against the credential policy, reporting all failing rules at once
+	fieldErrors := map[string][]string{}
+	if newUser.Username == "" {
+		fieldErrors["username"] = []string{"is required"}
+	} else if problems := CredentialPolicy.CheckUsername(newUser.Username); len(problems) > 0 {
+		fieldErrors["username"] = problems
+	}
+	if newUser.Password == "" {
+		fieldErrors["password"] = []string{"is required"}
+	} else if problems := CredentialPolicy.CheckPassword(newUser.Password, newUser.Username); len(problems) > 0 {
+		fieldErrors["password"] = problems
+	}
+	if newUser.Email != "" && !validEmail(newUser.Email) {
+		fieldErrors["email"] = []string{"must be a valid email address"}
+	}
+
+	if len(fieldErrors) > 0 {
 		responseCode = http.StatusBadRequest
 		context.JSON(responseCode, gin.H{
-			"detail": "Username and password cannot be empty",
+			"detail": "Registration is invalid",
+			"errors": fieldErrors,
 		})
 		return
 	}
 
 	// Store user (password will be hashed inside repository)
-	creationError := repository.StoreUser(database.DbConnection, newUser)
+	userID, creationError := repository.StoreUser(context.Request.Context(), database.DbConnection, newUser)
 
 	if creationError.Message != "" {
 		responseCode = creationError.Status
-		context.JSON(responseCode, gin.H{
+		response := gin.H{
 			"detail": creationError.Message,
+		}
+		if creationError.Status == http.StatusConflict {
+			field := "username"
+			if strings.HasPrefix(creationError.Message, "email") {
+				field = "email"
+			}
+			response["errors"] = map[string][]string{field: {"is already taken"}}
+		}
+		context.JSON(responseCode, response)
+		return
+	}
+	metrics.UsersRegistered.Inc()
+
+	message := "User registered successfully"
+	if newUser.Email != "" {
+		// The account exists either way; a failed email can be sent again from POST /me/email/verification
+		if sendError := sendVerificationEmail(context.Request.Context(), userID, newUser.Username, newUser.Email); sendError.Message != "" {
+			slog.WarnContext(context.Request.Context(), "failed to send verification email", "target_user_id", userID, "error", sendError.Message)
+			message = "User registered successfully, but the verification email could not be sent"
+		} else {
+			message = "User registered successfully, check your email to verify your address"
+		}
+	}
+
+	context.JSON(responseCode, gin.H{
+		"status":  "success",
+		"message": message,
+	})
+}
+
+const (
+	maxDisplayNameLength = 100
+	maxBioLength         = 2000
+	maxAvatarURLLength   = 255
+	recentReviewsLimit   = 5
+)
+
+// HandleGetMe returns the authenticated user's own profile, including privacy settings.
+func HandleGetMe(context *gin.Context) {
+	responseCode := http.StatusOK
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	profile, retrievalError := repository.FindUserByID(context.Request.Context(), database.DbConnection, userRecord.ID)
+
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
 		})
 		return
 	}
 
+	context.JSON(responseCode, gin.H{
+		"item": profile,
+	})
+}
+
+// HandleUpdateMe changes the authenticated user's display name, bio, avatar and privacy settings.
+func HandleUpdateMe(context *gin.Context) {
+	responseCode := http.StatusOK
+	currentUser, exists := context.Get("user")
+	if !exists {
+		responseCode = http.StatusUnauthorized
+		context.JSON(responseCode, gin.H{
+			"detail": "Authentication required",
+		})
+		return
+	}
+	userRecord, ok := currentUser.(structs.User)
+	if !ok {
+		responseCode = http.StatusInternalServerError
+		context.JSON(responseCode, gin.H{
+			"detail": "Failed to get user data from context",
+		})
+		return
+	}
+
+	var profileUpdate structs.ProfileUpdate
+	if bindError := context.ShouldBindJSON(&profileUpdate); bindError != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": bindError.Error(),
+		})
+		return
+	}
+
+	if detail := validateProfileUpdate(profileUpdate); detail != "" {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": detail,
+		})
+		return
+	}
+
+	previous, retrievalError := repository.FindUserByID(context.Request.Context(), database.DbConnection, userRecord.ID)
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	updateError := repository.UpdateUserProfile(context.Request.Context(), database.DbConnection, userRecord.ID, profileUpdate)
+
+	if updateError.Message != "" {
+		responseCode = updateError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": updateError.Message,
+		})
+		return
+	}
+
+	message := "Profile updated successfully"
+	if profileUpdate.Email != nil && *profileUpdate.Email != "" && !strings.EqualFold(*profileUpdate.Email, previous.Email) {
+		if sendError := sendVerificationEmail(context.Request.Context(), userRecord.ID, previous.Username, *profileUpdate.Email); sendError.Message != "" {
+			slog.WarnContext(context.Request.Context(), "failed to send verification email", "target_user_id", userRecord.ID, "error", sendError.Message)
+			message = "Profile updated successfully, but the verification email could not be sent"
+		} else {
+			message = "Profile updated successfully, check your email to verify your new address"
+		}
+	}
+
 	context.JSON(responseCode, gin.H{
 		"status":  "success",
-		"message": "User registered successfully",
+		"message": message,
 	})
-}
\ No newline at end of file
+}
+
+// HandleGetUserProfile returns a user's public profile with review stats and recent reviews,
+// as far as their privacy settings allow.
+func HandleGetUserProfile(context *gin.Context) {
+	responseCode := http.StatusOK
+	userID, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		responseCode = http.StatusBadRequest
+		context.JSON(responseCode, gin.H{
+			"detail": "Invalid user ID",
+		})
+		return
+	}
+
+	userRecord, retrievalError := repository.FindUserByID(context.Request.Context(), database.DbConnection, userID)
+	if retrievalError.Message != "" {
+		responseCode = retrievalError.Status
+		context.JSON(responseCode, gin.H{
+			"detail": retrievalError.Message,
+		})
+		return
+	}
+
+	profile := structs.PublicProfile{
+		ID:          userRecord.ID,
+		Username:    userRecord.Username,
+		DisplayName: userRecord.DisplayName,
+		AvatarURL:   userRecord.AvatarURL,
+		CreatedAt:   userRecord.CreatedAt,
+		Private:     !userRecord.ProfilePublic,
+	}
+
+	if userRecord.ProfilePublic {
+		reviewCount, averageRating, statsError := repository.RetrieveUserReviewStats(context.Request.Context(), database.DbConnection, userID)
+		if statsError.Message != "" {
+			responseCode = statsError.Status
+			context.JSON(responseCode, gin.H{
+				"detail": statsError.Message,
+			})
+			return
+		}
+		profile.Bio = userRecord.Bio
+		profile.ReviewCount = &reviewCount
+		profile.AverageRatingGiven = &averageRating
+	}
+
+	if userRecord.ProfilePublic && userRecord.ShowReviews {
+		recentReviews, reviewsError := repository.RetrieveRecentReviewsByUser(context.Request.Context(), database.DbConnection, userID, recentReviewsLimit)
+		if reviewsError.Message != "" {
+			responseCode = reviewsError.Status
+			context.JSON(responseCode, gin.H{
+				"detail": reviewsError.Message,
+			})
+			return
+		}
+		for i := range recentReviews {
+			presentReview(&recentReviews[i], false)
+		}
+		profile.RecentReviews = recentReviews
+	}
+
+	context.JSON(responseCode, gin.H{
+		"item": profile,
+	})
+}
+
+// validateProfileUpdate returns why a profile update is invalid, or "" when it is fine. It trims the
+// email address in place.
+func validateProfileUpdate(profileUpdate structs.ProfileUpdate) string {
+	if profileUpdate.DisplayName != nil && utf8.RuneCountInString(*profileUpdate.DisplayName) > maxDisplayNameLength {
+		return "Display name cannot be longer than " + strconv.Itoa(maxDisplayNameLength) + " characters"
+	}
+	if profileUpdate.Bio != nil && utf8.RuneCountInString(*profileUpdate.Bio) > maxBioLength {
+		return "Bio cannot be longer than " + strconv.Itoa(maxBioLength) + " characters"
+	}
+	if profileUpdate.Email != nil {
+		trimmed := strings.TrimSpace(*profileUpdate.Email)
+		if trimmed != "" && !validEmail(trimmed) {
+			return "Email must be a valid email address"
+		}
+		*profileUpdate.Email = trimmed
+	}
+	if profileUpdate.AvatarURL != nil && *profileUpdate.AvatarURL != "" {
+		if len(*profileUpdate.AvatarURL) > maxAvatarURLLength {
+			return "Avatar URL cannot be longer than " + strconv.Itoa(maxAvatarURLLength) + " characters"
+		}
+		parsed, err := url.Parse(*profileUpdate.AvatarURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return "Avatar URL must be an http or https URL"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,143 @@
+package credentials
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed common_passwords.txt
+var bundledCommonPasswords string
+
+// PasswordMaxBytes is the longest password bcrypt accepts; it rejects anything longer.
+const PasswordMaxBytes = 72
+
+// Policy holds the rules usernames and passwords have to follow.
+type Policy struct {
+	UsernameMinLength  int
+	UsernameMaxLength  int
+	UsernamePattern    *regexp.Regexp // Allowed characters
+	PasswordMinLength  int
+	PasswordMinClasses int             // How many of lowercase, uppercase, digits and symbols a password needs
+	CommonPasswords    map[string]bool // Rejected passwords, lowercased
+}
+
+// DefaultPolicy returns the rules used when nothing is configured, with the bundled list of common
+// passwords.
+func DefaultPolicy() Policy {
+	return Policy{
+		UsernameMinLength:  3,
+		UsernameMaxLength:  30,
+		UsernamePattern:    regexp.MustCompile(`^[A-Za-z0-9_.-]+$`),
+		PasswordMinLength:  8,
+		PasswordMinClasses: 3,
+		CommonPasswords:    parseList(bundledCommonPasswords),
+	}
+}
+
+// LoadCommonPasswords reads a list of common passwords from a file, one per line.
+func LoadCommonPasswords(path string) (map[string]bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseList(string(content)), nil
+}
+
+// Check returns why the policy is not usable, or nil when it is fine.
+func (p Policy) Check() error {
+	if p.UsernameMinLength < 1 || p.UsernameMaxLength < p.UsernameMinLength {
+		return fmt.Errorf("username length must allow at least one character (min %d, max %d)", p.UsernameMinLength, p.UsernameMaxLength)
+	}
+	if p.UsernameMaxLength > 100 {
+		return fmt.Errorf("username max length cannot exceed 100, the size of the username column")
+	}
+	if p.UsernamePattern == nil {
+		return fmt.Errorf("username pattern is missing")
+	}
+	if p.PasswordMinLength < 1 {
+		return fmt.Errorf("password min length must be at least 1")
+	}
+	if p.PasswordMinLength > PasswordMaxBytes {
+		return fmt.Errorf("password min length cannot exceed %d, the most bcrypt accepts", PasswordMaxBytes)
+	}
+	if p.PasswordMinClasses < 0 || p.PasswordMinClasses > 4 {
+		return fmt.Errorf("password min classes must be between 0 and 4")
+	}
+	return nil
+}
+
+// CheckUsername returns every rule the username breaks.
+func (p Policy) CheckUsername(username string) []string {
+	problems := []string{}
+	length := utf8.RuneCountInString(username)
+	if length < p.UsernameMinLength || length > p.UsernameMaxLength {
+		problems = append(problems, fmt.Sprintf("must be between %d and %d characters", p.UsernameMinLength, p.UsernameMaxLength))
+	}
+	if username != "" && !p.UsernamePattern.MatchString(username) {
+		problems = append(problems, fmt.Sprintf("contains characters that are not allowed (must match %s)", p.UsernamePattern))
+	}
+	return problems
+}
+
+// CheckPassword returns every rule the password breaks. username may be empty when it is not known.
+func (p Policy) CheckPassword(password string, username string) []string {
+	problems := []string{}
+	if utf8.RuneCountInString(password) < p.PasswordMinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", p.PasswordMinLength))
+	}
+	if len(password) > PasswordMaxBytes {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes", PasswordMaxBytes))
+	}
+	if countClasses(password) < p.PasswordMinClasses {
+		problems = append(problems, fmt.Sprintf("must contain at least %d of: lowercase letters, uppercase letters, digits, symbols", p.PasswordMinClasses))
+	}
+	if p.CommonPasswords[strings.ToLower(password)] {
+		problems = append(problems, "is too common")
+	}
+	if username != "" && strings.EqualFold(password, username) {
+		problems = append(problems, "cannot be the same as the username")
+	}
+	return problems
+}
+
+func countClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	count := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+func parseList(content string) map[string]bool {
+	entries := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries[strings.ToLower(line)] = true
+	}
+	return entries
+}
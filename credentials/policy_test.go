@@ -0,0 +1,104 @@
+package credentials
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheckPassword(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.CommonPasswords = map[string]bool{"password1!": true}
+
+	tests := []struct {
+		name     string
+		password string
+		username string
+		want     []string
+	}{
+		{"valid", "Tr1cky-Horse", "alice", []string{}},
+		{"too short", "Ab1!", "", []string{"must be at least 8 characters"}},
+		{"length counts characters, not bytes", "Äöü1ßéèà", "", []string{}},
+		{"exactly 72 bytes", strings.Repeat("Ab1", 24), "", []string{}},
+		{"over 72 bytes", strings.Repeat("Ab1", 24) + "x", "", []string{"must be at most 72 bytes"}},
+		{"over 72 bytes in multibyte characters", strings.Repeat("Ä1a", 19), "", []string{"must be at most 72 bytes"}},
+		{"too few classes", "lowercase123", "", []string{"must contain at least 3 of: lowercase letters, uppercase letters, digits, symbols"}},
+		{"common, ignoring case", "PASSWORD1!", "", []string{"is too common"}},
+		{"same as the username, ignoring case", "Alice.Smith9", "alice.smith9", []string{"cannot be the same as the username"}},
+		{"several rules", "abc", "abc", []string{
+			"must be at least 8 characters",
+			"must contain at least 3 of: lowercase letters, uppercase letters, digits, symbols",
+			"cannot be the same as the username",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := policy.CheckPassword(test.password, test.username); !slices.Equal(got, test.want) {
+				t.Errorf("CheckPassword(%q, %q) = %q, want %q", test.password, test.username, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckUsername(t *testing.T) {
+	policy := DefaultPolicy()
+
+	tests := []struct {
+		name     string
+		username string
+		want     []string
+	}{
+		{"valid", "alice.smith_9", []string{}},
+		{"too short", "al", []string{"must be between 3 and 30 characters"}},
+		{"too long", strings.Repeat("a", 31), []string{"must be between 3 and 30 characters"}},
+		{"not allowed characters", "alice smith", []string{"contains characters that are not allowed (must match ^[A-Za-z0-9_.-]+$)"}},
+		{"empty", "", []string{"must be between 3 and 30 characters"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := policy.CheckUsername(test.username); !slices.Equal(got, test.want) {
+				t.Errorf("CheckUsername(%q) = %q, want %q", test.username, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(*Policy)
+		wantErr bool
+	}{
+		{"default", func(*Policy) {}, false},
+		{"username max below min", func(p *Policy) { p.UsernameMaxLength = 2 }, true},
+		{"username max over the column size", func(p *Policy) { p.UsernameMaxLength = 101 }, true},
+		{"missing username pattern", func(p *Policy) { p.UsernamePattern = nil }, true},
+		{"password min length of 0", func(p *Policy) { p.PasswordMinLength = 0 }, true},
+		{"password min length of 72", func(p *Policy) { p.PasswordMinLength = 72 }, false},
+		{"password min length over 72", func(p *Policy) { p.PasswordMinLength = 73 }, true},
+		{"password min classes over 4", func(p *Policy) { p.PasswordMinClasses = 5 }, true},
+		{"custom username pattern", func(p *Policy) { p.UsernamePattern = regexp.MustCompile(`^[a-z]+$`) }, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := DefaultPolicy()
+			test.change(&policy)
+			if err := policy.Check(); (err != nil) != test.wantErr {
+				t.Errorf("Check() = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestBundledCommonPasswords(t *testing.T) {
+	policy := DefaultPolicy()
+	if len(policy.CommonPasswords) == 0 {
+		t.Fatal("the bundled list of common passwords is empty")
+	}
+	for entry := range policy.CommonPasswords {
+		if entry != strings.ToLower(entry) || strings.HasPrefix(entry, "#") || strings.TrimSpace(entry) != entry {
+			t.Errorf("common password %q was not normalized", entry)
+		}
+	}
+}
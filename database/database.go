@@ -3,7 +3,8 @@ package database
 import (
 	"database/sql"
 	"embed"
-	"fmt"
+	"log/slog"
+
 	migrate "github.com/rubenv/sql-migrate"
 )
 
@@ -11,18 +12,21 @@ import (
 var dbMigrations embed.FS
 var DbConnection *sql.DB
 
-func DBMigrate(dbParam *sql.DB)  {
-	migrations := &migrate.EmbedFileSystemMigrationSource{
+// migrationSource returns the embedded schema migrations.
+func migrationSource() *migrate.EmbedFileSystemMigrationSource {
+	return &migrate.EmbedFileSystemMigrationSource{
 		FileSystem: dbMigrations,
-		Root: "sql_migrations",
+		Root:       "sql_migrations",
 	}
+}
 
-	n, errs := migrate.Exec(dbParam, "postgres", migrations, migrate.Up)
+func DBMigrate(dbParam *sql.DB) {
+	n, errs := migrate.Exec(dbParam, "postgres", migrationSource(), migrate.Up)
 	if errs != nil {
 		panic(errs)
 	}
 
 	DbConnection = dbParam
 
-	fmt.Println("Migration success, applied", n, "migrations!")
-}
\ No newline at end of file
+	slog.Info("migrations applied", "count", n)
+}
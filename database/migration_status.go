@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// MigrationStatus compares the embedded migrations with the ones sql-migrate recorded as applied
+// in its gorp_migrations table. latest is the ID of the newest applied migration and pending lists
+// the embedded migrations that have not been applied, in order.
+func MigrationStatus(ctx context.Context, db *sql.DB) (latest string, pending []string, err error) {
+	migrations, err := migrationSource().FindMigrations()
+	if err != nil {
+		return "", nil, err
+	}
+
+	rowsData, err := db.QueryContext(ctx, "SELECT id FROM gorp_migrations")
+	if err != nil {
+		return "", nil, err
+	}
+	defer rowsData.Close()
+
+	applied := map[string]bool{}
+	for rowsData.Next() {
+		var id string
+		if err := rowsData.Scan(&id); err != nil {
+			return "", nil, err
+		}
+		applied[id] = true
+	}
+	if err := rowsData.Err(); err != nil {
+		return "", nil, err
+	}
+
+	// FindMigrations sorts by the numeric prefix, so the last applied one is the newest
+	pending = []string{}
+	for _, migration := range migrations {
+		if applied[migration.Id] {
+			latest = migration.Id
+		} else {
+			pending = append(pending, migration.Id)
+		}
+	}
+	return latest, pending, nil
+}
@@ -0,0 +1,26 @@
+-- 10_.half_star_ratings.sql
+
+-- +migrate Up
+-- Ratings become decimals in 0.5 steps; existing integer ratings are kept as-is (4 -> 4.0).
+-- The allowed range is configured in the application (RATING_MIN, RATING_MAX, RATING_STEP).
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS reviews_rating_check;
+ALTER TABLE reviews ALTER COLUMN rating TYPE NUMERIC(3,1) USING rating::NUMERIC(3,1);
+ALTER TABLE reviews ADD CONSTRAINT reviews_rating_check CHECK (rating > 0 AND rating * 2 = TRUNC(rating * 2));
+
+ALTER TABLE review_aspect_ratings DROP CONSTRAINT IF EXISTS review_aspect_ratings_rating_check;
+ALTER TABLE review_aspect_ratings ALTER COLUMN rating TYPE NUMERIC(3,1) USING rating::NUMERIC(3,1);
+ALTER TABLE review_aspect_ratings ADD CONSTRAINT review_aspect_ratings_rating_check CHECK (rating > 0 AND rating * 2 = TRUNC(rating * 2));
+
+ALTER TABLE review_revisions ALTER COLUMN rating TYPE NUMERIC(3,1) USING rating::NUMERIC(3,1);
+
+-- +migrate Down
+-- Half-star ratings are rounded up to the nearest whole star
+ALTER TABLE review_revisions ALTER COLUMN rating TYPE INT USING CEIL(rating)::INT;
+
+ALTER TABLE review_aspect_ratings DROP CONSTRAINT IF EXISTS review_aspect_ratings_rating_check;
+ALTER TABLE review_aspect_ratings ALTER COLUMN rating TYPE INT USING LEAST(GREATEST(CEIL(rating), 1), 5)::INT;
+ALTER TABLE review_aspect_ratings ADD CONSTRAINT review_aspect_ratings_rating_check CHECK (rating >= 1 AND rating <= 5);
+
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS reviews_rating_check;
+ALTER TABLE reviews ALTER COLUMN rating TYPE INT USING LEAST(GREATEST(CEIL(rating), 1), 5)::INT;
+ALTER TABLE reviews ADD CONSTRAINT reviews_rating_check CHECK (rating >= 1 AND rating <= 5);
@@ -0,0 +1,16 @@
+-- 11_.user_profiles.sql
+
+-- +migrate Up
+-- Profile fields and privacy settings
+ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS bio TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_url VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS profile_public BOOLEAN NOT NULL DEFAULT TRUE; -- Show bio and review stats on GET /users/:id
+ALTER TABLE users ADD COLUMN IF NOT EXISTS show_reviews BOOLEAN NOT NULL DEFAULT TRUE; -- Show recent reviews on GET /users/:id
+
+-- +migrate Down
+ALTER TABLE users DROP COLUMN IF EXISTS show_reviews;
+ALTER TABLE users DROP COLUMN IF EXISTS profile_public;
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_url;
+ALTER TABLE users DROP COLUMN IF EXISTS bio;
+ALTER TABLE users DROP COLUMN IF EXISTS display_name;
@@ -0,0 +1,25 @@
+-- 12_.account_email.sql
+
+-- +migrate Up
+-- Email address (unique regardless of case) and whether its owner has confirmed it
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(255);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (LOWER(email));
+
+-- User Tokens Table (single-use, time-limited tokens for password resets and email verification)
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    purpose VARCHAR(30) NOT NULL, -- 'password_reset' or 'email_verification'
+    token_hash CHAR(64) NOT NULL UNIQUE, -- SHA-256 of the token; the token itself is only sent by email
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS user_tokens;
+DROP INDEX IF EXISTS users_email_lower_key;
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified;
+ALTER TABLE users DROP COLUMN IF EXISTS email;
@@ -0,0 +1,31 @@
+-- 13_.two_factor.sql
+
+-- +migrate Up
+-- TOTP two-factor authentication; the secret is set on enrollment and enabled once confirmed
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Recovery Codes Table (single-use codes accepted instead of a TOTP code)
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    code_hash CHAR(64) NOT NULL, -- SHA-256 of the normalized code
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (user_id, code_hash)
+);
+
+-- Role Settings Table (per-role security policies set by admins)
+CREATE TABLE IF NOT EXISTS role_settings (
+    role VARCHAR(20) PRIMARY KEY,
+    require_two_factor BOOLEAN NOT NULL DEFAULT FALSE,
+    modified_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    modified_by INT -- References users.id
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS role_settings;
+DROP TABLE IF EXISTS recovery_codes;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_enabled;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_secret;
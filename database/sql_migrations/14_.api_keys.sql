@@ -0,0 +1,21 @@
+-- 14_.api_keys.sql
+
+-- +migrate Up
+-- API Keys Table (personal keys for scripts, sent in the X-API-Key header)
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL, -- Start of the key, to tell keys apart
+    key_hash CHAR(64) NOT NULL UNIQUE, -- SHA-256 of the key; the key itself is only shown on creation
+    scopes TEXT[] NOT NULL, -- 'read', 'reviews' and/or 'catalog-write'
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys (user_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS api_keys;
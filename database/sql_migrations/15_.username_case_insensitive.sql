@@ -0,0 +1,9 @@
+-- 15_.username_case_insensitive.sql
+
+-- +migrate Up
+-- Usernames are unique regardless of case ("Nabiel" and "nabiel" cannot both exist).
+-- Fails if such duplicates already exist; rename them before upgrading.
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_lower_key ON users (LOWER(username));
+
+-- +migrate Down
+DROP INDEX IF EXISTS users_username_lower_key;
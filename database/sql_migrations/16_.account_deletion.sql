@@ -0,0 +1,15 @@
+-- 16_.account_deletion.sql
+
+-- +migrate Up
+-- Scheduled account deletion; the account is erased once deletion_scheduled_for has passed
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_scheduled_for TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_review_mode VARCHAR(20); -- 'delete' or 'anonymize' the user's reviews
+
+-- Anonymized reviews outlive their author
+ALTER TABLE reviews ALTER COLUMN user_id DROP NOT NULL;
+
+-- +migrate Down
+DELETE FROM reviews WHERE user_id IS NULL;
+ALTER TABLE reviews ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE users DROP COLUMN IF EXISTS deletion_review_mode;
+ALTER TABLE users DROP COLUMN IF EXISTS deletion_scheduled_for;
@@ -0,0 +1,9 @@
+-- 19_.anonymous_comments.sql
+
+-- +migrate Up
+-- Comments that other users replied to outlive their author, so erasing an account keeps the replies
+ALTER TABLE review_comments ALTER COLUMN user_id DROP NOT NULL;
+
+-- +migrate Down
+DELETE FROM review_comments WHERE user_id IS NULL;
+ALTER TABLE review_comments ALTER COLUMN user_id SET NOT NULL;
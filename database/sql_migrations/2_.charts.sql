@@ -0,0 +1,24 @@
+-- 2_.charts.sql
+
+-- +migrate Up
+-- Chart Entries Table (materialized top-rated and trending charts)
+CREATE TABLE IF NOT EXISTS chart_entries (
+    id SERIAL PRIMARY KEY,
+    chart VARCHAR(20) NOT NULL, -- 'top_rated' or 'trending'
+    category_id INT NOT NULL DEFAULT 0, -- 0 for the chart across all categories
+    book_id INT NOT NULL,
+    rank INT NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    review_count INT NOT NULL,
+    average_rating DOUBLE PRECISION NOT NULL,
+    computed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE,
+    UNIQUE (chart, category_id, book_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chart_entries_lookup ON chart_entries (chart, category_id, rank);
+CREATE INDEX IF NOT EXISTS idx_reviews_created_at ON reviews (created_at);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_reviews_created_at;
+DROP TABLE IF EXISTS chart_entries;
@@ -0,0 +1,20 @@
+-- 3_.review_votes.sql
+
+-- +migrate Up
+-- Review Votes Table (helpful / unhelpful votes on reviews)
+CREATE TABLE IF NOT EXISTS review_votes (
+    id SERIAL PRIMARY KEY,
+    review_id INT NOT NULL,
+    user_id INT NOT NULL,
+    helpful BOOLEAN NOT NULL, -- TRUE for helpful, FALSE for unhelpful
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    created_by INT, -- References users.id
+    modified_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    modified_by INT, -- References users.id
+    FOREIGN KEY (review_id) REFERENCES reviews(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (review_id, user_id) -- A user can only vote on a review once
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS review_votes;
@@ -0,0 +1,24 @@
+-- 4_.review_comments.sql
+
+-- +migrate Up
+-- Review Comments Table (comments on reviews, with a single level of replies)
+CREATE TABLE IF NOT EXISTS review_comments (
+    id SERIAL PRIMARY KEY,
+    review_id INT NOT NULL,
+    user_id INT NOT NULL,
+    parent_id INT, -- NULL for top-level comments, otherwise the top-level comment being replied to
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    created_by INT, -- References users.id
+    modified_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    modified_by INT, -- References users.id
+    FOREIGN KEY (review_id) REFERENCES reviews(id) ON DELETE CASCADE, -- Deleting a review removes its comments
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (parent_id) REFERENCES review_comments(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_comments_review_id ON review_comments (review_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_review_comments_parent_id ON review_comments (parent_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS review_comments;
@@ -0,0 +1,31 @@
+-- 5_.moderation.sql
+
+-- +migrate Up
+-- User roles: 'user', 'moderator' or 'admin'
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user';
+
+-- Hidden reviews are excluded from public listings
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS hidden BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Review Reports Table
+CREATE TABLE IF NOT EXISTS review_reports (
+    id SERIAL PRIMARY KEY,
+    review_id INT NOT NULL,
+    user_id INT NOT NULL, -- The reporting user
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open', -- 'open' or 'resolved'
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    created_by INT, -- References users.id
+    modified_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    modified_by INT, -- References users.id
+    FOREIGN KEY (review_id) REFERENCES reviews(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (review_id, user_id) -- A user can only report a review once
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_reports_status ON review_reports (status, review_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS review_reports;
+ALTER TABLE reviews DROP COLUMN IF EXISTS hidden;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
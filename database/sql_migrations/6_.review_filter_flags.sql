@@ -0,0 +1,9 @@
+-- 6_.review_filter_flags.sql
+
+-- +migrate Up
+-- Reports raised by the review content filter have no reporting user
+ALTER TABLE review_reports ALTER COLUMN user_id DROP NOT NULL;
+
+-- +migrate Down
+DELETE FROM review_reports WHERE user_id IS NULL;
+ALTER TABLE review_reports ALTER COLUMN user_id SET NOT NULL;
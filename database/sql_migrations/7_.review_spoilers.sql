@@ -0,0 +1,8 @@
+-- 7_.review_spoilers.sql
+
+-- +migrate Up
+-- Marks the whole review as a spoiler (inline spoilers use ||hidden text|| markup in the comment)
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS contains_spoilers BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +migrate Down
+ALTER TABLE reviews DROP COLUMN IF EXISTS contains_spoilers;
@@ -0,0 +1,20 @@
+-- 8_.review_revisions.sql
+
+-- +migrate Up
+-- Review Revisions Table (every prior version of a review, written before it is overwritten)
+CREATE TABLE IF NOT EXISTS review_revisions (
+    id SERIAL PRIMARY KEY,
+    review_id INT NOT NULL,
+    revision INT NOT NULL, -- 1 for the original text, counting up with every edit
+    rating INT NOT NULL,
+    comment TEXT,
+    contains_spoilers BOOLEAN NOT NULL DEFAULT FALSE,
+    written_at TIMESTAMP WITH TIME ZONE NOT NULL, -- When this version was written
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(), -- When this version was replaced
+    created_by INT, -- References users.id, the user who replaced it
+    FOREIGN KEY (review_id) REFERENCES reviews(id) ON DELETE CASCADE,
+    UNIQUE (review_id, revision)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS review_revisions;
@@ -0,0 +1,53 @@
+-- 9_.rating_aspects.sql
+
+-- +migrate Up
+-- Rating Aspects Table (optional sub-ratings such as plot or pacing)
+CREATE TABLE IF NOT EXISTS rating_aspects (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) UNIQUE NOT NULL, -- Key used in review payloads, e.g. 'plot'
+    name VARCHAR(100) NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE, -- Used by categories without their own aspect set
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    created_by INT, -- References users.id
+    modified_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    modified_by INT -- References users.id
+);
+
+-- Per-category aspect sets
+CREATE TABLE IF NOT EXISTS category_rating_aspects (
+    category_id INT NOT NULL,
+    aspect_id INT NOT NULL,
+    PRIMARY KEY (category_id, aspect_id),
+    FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE,
+    FOREIGN KEY (aspect_id) REFERENCES rating_aspects(id) ON DELETE CASCADE
+);
+
+-- Sub-ratings given in a review
+CREATE TABLE IF NOT EXISTS review_aspect_ratings (
+    review_id INT NOT NULL,
+    aspect_id INT NOT NULL,
+    rating INT NOT NULL CHECK (rating >= 1 AND rating <= 5), -- Rating from 1 to 5
+    PRIMARY KEY (review_id, aspect_id),
+    FOREIGN KEY (review_id) REFERENCES reviews(id) ON DELETE CASCADE,
+    FOREIGN KEY (aspect_id) REFERENCES rating_aspects(id) ON DELETE CASCADE
+);
+
+-- Insert Default Aspects
+INSERT INTO rating_aspects (code, name, is_default, created_by, modified_by) VALUES
+('plot', 'Plot', TRUE, 0, 0),
+('characters', 'Characters', TRUE, 0, 0),
+('writing_style', 'Writing Style', TRUE, 0, 0),
+('pacing', 'Pacing', TRUE, 0, 0),
+('informativeness', 'Informativeness', FALSE, 0, 0)
+ON CONFLICT (code) DO NOTHING;
+
+-- Non-Fiction books are rated on writing, pacing and how informative they are
+INSERT INTO category_rating_aspects (category_id, aspect_id)
+SELECT c.id, a.id FROM categories c, rating_aspects a
+WHERE c.name = 'Non-Fiction' AND a.code IN ('writing_style', 'pacing', 'informativeness')
+ON CONFLICT DO NOTHING;
+
+-- +migrate Down
+DROP TABLE IF EXISTS review_aspect_ratings;
+DROP TABLE IF EXISTS category_rating_aspects;
+DROP TABLE IF EXISTS rating_aspects;
@@ -0,0 +1,129 @@
+package dataexport
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sb-go-readrate-nabiel/structs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Archive is everything stored about one user.
+type Archive struct {
+	Profile    structs.User
+	Reviews    []structs.Review
+	Activity   []structs.ActivityEntry
+	ExportedAt time.Time
+}
+
+// Write writes the archive as a ZIP file holding each part both as JSON and as CSV.
+func Write(w io.Writer, archive Archive) error {
+	zipWriter := zip.NewWriter(w)
+
+	readme := fmt.Sprintf("ReadRate data export for %s, created %s.\n\n"+
+		"profile.json / profile.csv    your account and profile settings\n"+
+		"reviews.json / reviews.csv    every review you wrote, including hidden ones\n"+
+		"activity.json / activity.csv  your votes, comments and reports\n",
+		archive.Profile.Username, archive.ExportedAt.Format(time.RFC3339))
+	if err := writeFile(zipWriter, "README.txt", archive.ExportedAt, []byte(readme)); err != nil {
+		return err
+	}
+
+	profile := archive.Profile
+	if err := writeJSON(zipWriter, "profile.json", archive.ExportedAt, profile); err != nil {
+		return err
+	}
+	deletion := ""
+	if profile.DeletionScheduledFor != nil {
+		deletion = profile.DeletionScheduledFor.Format(time.RFC3339)
+	}
+	profileRows := [][]string{
+		{"id", "username", "email", "email_verified", "role", "display_name", "bio", "avatar_url",
+			"profile_public", "show_reviews", "two_factor_enabled", "created_at", "deletion_scheduled_for"},
+		{strconv.Itoa(profile.ID), profile.Username, profile.Email, strconv.FormatBool(profile.EmailVerified), profile.Role,
+			profile.DisplayName, profile.Bio, profile.AvatarURL, strconv.FormatBool(profile.ProfilePublic),
+			strconv.FormatBool(profile.ShowReviews), strconv.FormatBool(profile.TwoFactorEnabled),
+			profile.CreatedAt.Format(time.RFC3339), deletion},
+	}
+	if err := writeCSV(zipWriter, "profile.csv", archive.ExportedAt, profileRows); err != nil {
+		return err
+	}
+
+	if err := writeJSON(zipWriter, "reviews.json", archive.ExportedAt, archive.Reviews); err != nil {
+		return err
+	}
+	reviewRows := [][]string{{"id", "book_id", "rating", "comment", "contains_spoilers", "hidden", "aspect_ratings",
+		"helpful_count", "unhelpful_count", "comment_count", "created_at", "modified_at"}}
+	for _, review := range archive.Reviews {
+		reviewRows = append(reviewRows, []string{
+			strconv.Itoa(review.ID), strconv.Itoa(review.BookID), strconv.FormatFloat(review.Rating, 'f', -1, 64),
+			review.Comment, strconv.FormatBool(review.ContainsSpoilers), strconv.FormatBool(review.Hidden),
+			formatAspectRatings(review.AspectRatings), strconv.Itoa(review.HelpfulCount), strconv.Itoa(review.UnhelpfulCount),
+			strconv.Itoa(review.CommentCount), review.CreatedAt.Format(time.RFC3339), review.ModifiedAt.Format(time.RFC3339),
+		})
+	}
+	if err := writeCSV(zipWriter, "reviews.csv", archive.ExportedAt, reviewRows); err != nil {
+		return err
+	}
+
+	if err := writeJSON(zipWriter, "activity.json", archive.ExportedAt, archive.Activity); err != nil {
+		return err
+	}
+	activityRows := [][]string{{"type", "review_id", "detail", "created_at"}}
+	for _, activity := range archive.Activity {
+		activityRows = append(activityRows, []string{
+			activity.Type, strconv.Itoa(activity.ReviewID), activity.Detail, activity.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	if err := writeCSV(zipWriter, "activity.csv", archive.ExportedAt, activityRows); err != nil {
+		return err
+	}
+
+	return zipWriter.Close()
+}
+
+func writeFile(zipWriter *zip.Writer, name string, modified time.Time, content []byte) error {
+	file, err := zipWriter.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	return err
+}
+
+func writeJSON(zipWriter *zip.Writer, name string, modified time.Time, value any) error {
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(zipWriter, name, modified, content)
+}
+
+func writeCSV(zipWriter *zip.Writer, name string, modified time.Time, rows [][]string) error {
+	var builder strings.Builder
+	csvWriter := csv.NewWriter(&builder)
+	if err := csvWriter.WriteAll(rows); err != nil {
+		return err
+	}
+	return writeFile(zipWriter, name, modified, []byte(builder.String()))
+}
+
+// formatAspectRatings renders sub-ratings as "code=rating" pairs in a stable order.
+func formatAspectRatings(aspectRatings map[string]float64) string {
+	codes := make([]string, 0, len(aspectRatings))
+	for code := range aspectRatings {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	pairs := make([]string, 0, len(codes))
+	for _, code := range codes {
+		pairs = append(pairs, code+"="+strconv.FormatFloat(aspectRatings[code], 'f', -1, 64))
+	}
+	return strings.Join(pairs, ";")
+}
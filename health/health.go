@@ -0,0 +1,104 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sb-go-readrate-nabiel/database"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Check is one dependency the service needs to serve requests.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Result is the outcome of a Check.
+type Result struct {
+	Name     string `json:"name"`
+	Healthy  bool   `json:"healthy"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Run runs every check concurrently, each bounded by ctx. ready is true only when all of them pass.
+// Results keep the order of checks.
+func Run(ctx context.Context, checks []Check) (results []Result, ready bool) {
+	results = make([]Result, len(checks))
+	var wait sync.WaitGroup
+	for i, check := range checks {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			started := time.Now()
+			err := check.Run(ctx)
+			results[i] = Result{Name: check.Name, Healthy: err == nil, Duration: time.Since(started).Round(time.Microsecond).String()}
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wait.Wait()
+
+	ready = true
+	for _, result := range results {
+		ready = ready && result.Healthy
+	}
+	return results, ready
+}
+
+// Database checks that PostgreSQL answers.
+func Database(db *sql.DB) Check {
+	return Check{Name: "database", Run: db.PingContext}
+}
+
+// Migrations checks that every embedded migration has been applied.
+func Migrations(db *sql.DB) Check {
+	return Check{Name: "migrations", Run: func(ctx context.Context) error {
+		_, pending, err := database.MigrationStatus(ctx, db)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migration(s): %s", len(pending), strings.Join(pending, ", "))
+		}
+		return nil
+	}}
+}
+
+// WritableFile checks that the file at path, or the directory it would be created in, exists and
+// has write permission. It only inspects them, so polling it does not create the file.
+func WritableFile(name string, path string) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		path := filepath.Clean(path)
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			// The file is created on first use, so its directory must take new files
+			path = filepath.Dir(path)
+			if info, err = os.Stat(path); err == nil && !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", path)
+			}
+		} else if err == nil && !info.Mode().IsRegular() {
+			return fmt.Errorf("%s is not a regular file", path)
+		}
+		if err != nil {
+			return err
+		}
+
+		if info.Mode().Perm()&0o222 == 0 {
+			return fmt.Errorf("%s is not writable", path)
+		}
+		return nil
+	}}
+}
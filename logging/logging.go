@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Setup makes slog write to output at the given level ("debug", "info", "warn" or "error") in the
+// given format ("json" or "text"), and becomes the default logger. Messages from the standard log
+// package go through it as well. Records logged with a request context carry its request ID, user ID
+// and, when the request is traced, its trace and span IDs.
+func Setup(output io.Writer, level string, format string) error {
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("unknown log level %q", level)
+	}
+
+	options := &slog.HandlerOptions{Level: logLevel}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		handler = slog.NewJSONHandler(output, options)
+	case "text":
+		handler = slog.NewTextHandler(output, options)
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+
+	slog.SetDefault(slog.New(requestHandler{handler}))
+	return nil
+}
+
+// request is what is known about the request being handled. The user ID is filled in once
+// authentication succeeds, after the context has been created.
+type request struct {
+	id     string
+	userID atomic.Int64
+}
+
+type requestKey struct{}
+
+// NewContext returns a copy of ctx that carries a request ID.
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestKey{}, &request{id: requestID})
+}
+
+// SetUserID records the authenticated user of the request carried by ctx.
+func SetUserID(ctx context.Context, userID int) {
+	if current, ok := ctx.Value(requestKey{}).(*request); ok {
+		current.userID.Store(int64(userID))
+	}
+}
+
+// RequestID returns the request ID carried by ctx, or an empty string.
+func RequestID(ctx context.Context) string {
+	if current, ok := ctx.Value(requestKey{}).(*request); ok {
+		return current.id
+	}
+	return ""
+}
+
+// requestHandler adds the request ID, user ID and trace IDs from the context to every record.
+type requestHandler struct {
+	slog.Handler
+}
+
+func (h requestHandler) Handle(ctx context.Context, record slog.Record) error {
+	if current, ok := ctx.Value(requestKey{}).(*request); ok {
+		record.AddAttrs(slog.String("request_id", current.id))
+		if userID := current.userID.Load(); userID != 0 {
+			record.AddAttrs(slog.Int64("user_id", userID))
+		}
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(slog.String("trace_id", spanContext.TraceID().String()), slog.String("span_id", spanContext.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h requestHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestHandler) WithGroup(name string) slog.Handler {
+	return requestHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,290 @@
+package loginguard
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options controls how failed logins are throttled.
+type Options struct {
+	MaxUsernameFailures int           // Failures after which an account is locked
+	MaxIPFailures       int           // Failures after which a client IP is locked
+	LockoutDuration     time.Duration // How long a lockout lasts
+	BaseDelay           time.Duration // Wait after the first failure, doubled with every further failure
+	MaxDelay            time.Duration // Upper bound of the backoff wait
+}
+
+// DefaultOptions returns the settings used when nothing is configured.
+func DefaultOptions() Options {
+	return Options{
+		MaxUsernameFailures: 5,
+		MaxIPFailures:       20,
+		LockoutDuration:     15 * time.Minute,
+		BaseDelay:           time.Second,
+		MaxDelay:            time.Minute,
+	}
+}
+
+// abandonAfter is how long an attempt may stay unsettled before it no longer counts, in case a
+// request never settled it.
+const abandonAfter = time.Minute
+
+type attempts struct {
+	failures    int
+	pending     int // Attempts begun but not settled yet
+	lastBegun   time.Time
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// Guard tracks failed logins per username and per client IP in memory. Every failure makes the
+// next attempt wait exponentially longer, and too many failures lock the username or IP for a
+// while. Attempts still being checked count against the limits too, so parallel requests cannot
+// slip past them. Counts are kept per process and are lost on restart.
+type Guard struct {
+	options Options
+	now     func() time.Time // Clock, replaced in tests
+
+	mu        sync.Mutex
+	usernames map[string]*attempts
+	ips       map[string]*attempts
+	lastSweep time.Time
+}
+
+// New creates a guard with the given options.
+func New(options Options) *Guard {
+	return &Guard{
+		options:   options,
+		now:       time.Now,
+		usernames: map[string]*attempts{},
+		ips:       map[string]*attempts{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Attempt is a login reserved by Begin. It must be settled with exactly one of Fail, Succeed or
+// Release; later calls do nothing. The methods of a nil Attempt do nothing either.
+type Attempt struct {
+	guard    *Guard
+	username string
+	ip       string
+	settled  bool
+}
+
+// Begin reserves a login for username from ip if one may be attempted now. Checking and reserving
+// happen in one step, so the attempts already under way are counted: once a username or IP has
+// failed, only one attempt at a time is let through, and never more than its remaining failures
+// before a lockout. When no attempt is allowed, retryAfter is how long the client has to wait and
+// locked tells whether a lockout (rather than the backoff delay) is the reason.
+func (g *Guard) Begin(username string, ip string) (attempt *Attempt, retryAfter time.Duration, locked bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	g.sweep(now)
+
+	key := normalize(username)
+	var lockWait, wait time.Duration
+	for _, entry := range []*attempts{g.current(g.usernames, key, now), g.current(g.ips, ip, now)} {
+		if remaining := entry.lockedUntil.Sub(now); remaining > 0 {
+			lockWait = max(lockWait, remaining)
+			continue
+		}
+		wait = max(wait, entry.lastFailure.Add(g.delay(entry.failures)).Sub(now))
+	}
+	if lockWait > 0 {
+		return nil, lockWait, true
+	}
+
+	usernameEntry, ipEntry := g.usernames[key], g.ips[ip]
+	if g.crowded(usernameEntry, g.options.MaxUsernameFailures) || g.crowded(ipEntry, g.options.MaxIPFailures) {
+		// Earlier attempts are still being checked; their outcome decides the next wait
+		wait = max(wait, g.delay(max(usernameEntry.failures, ipEntry.failures)+1), time.Second)
+	}
+	if wait > 0 {
+		return nil, wait, false
+	}
+
+	for _, entry := range []*attempts{usernameEntry, ipEntry} {
+		entry.pending++
+		entry.lastBegun = now
+	}
+	return &Attempt{guard: g, username: key, ip: ip}, 0, false
+}
+
+// Fail settles the attempt as a failed login, locking the username or IP once it has failed too
+// often. Lockouts are logged with ctx.
+func (a *Attempt) Fail(ctx context.Context) {
+	if a == nil {
+		return
+	}
+	g := a.guard
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !a.settle() {
+		return
+	}
+
+	now := g.now()
+	if g.fail(g.usernames, a.username, g.options.MaxUsernameFailures, now) {
+		slog.WarnContext(ctx, "account locked after too many failed logins",
+			"username", a.username, "lockout", g.options.LockoutDuration.String(), "failures", g.options.MaxUsernameFailures, "client_ip", a.ip)
+	}
+	if g.fail(g.ips, a.ip, g.options.MaxIPFailures, now) {
+		slog.WarnContext(ctx, "client IP locked after too many failed logins",
+			"client_ip", a.ip, "lockout", g.options.LockoutDuration.String(), "failures", g.options.MaxIPFailures, "username", a.username)
+	}
+}
+
+// Succeed settles the attempt as a successful login and clears the failures of the username. The
+// client IP keeps its count so one valid account cannot be used to keep guessing others.
+func (a *Attempt) Succeed() {
+	if a == nil {
+		return
+	}
+	g := a.guard
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if a.settle() {
+		g.clear(a.username)
+	}
+}
+
+// Release settles an attempt that neither failed nor succeeded, such as one cut short by a
+// database error, without counting it.
+func (a *Attempt) Release() {
+	if a == nil {
+		return
+	}
+	a.guard.mu.Lock()
+	defer a.guard.mu.Unlock()
+	a.settle()
+}
+
+// settle ends the reservation of the attempt and reports whether it was still open. The guard's
+// lock must be held.
+func (a *Attempt) settle() bool {
+	if a.settled {
+		return false
+	}
+	a.settled = true
+	for _, entry := range []*attempts{a.guard.usernames[a.username], a.guard.ips[a.ip]} {
+		if entry != nil && entry.pending > 0 {
+			entry.pending--
+		}
+	}
+	return true
+}
+
+// Unlock lifts the lockout and clears the failures of username. It reports whether there was
+// anything to clear.
+func (g *Guard) Unlock(username string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	key := normalize(username)
+	entry, tracked := g.usernames[key]
+	tracked = tracked && (entry.failures > 0 || !entry.lockedUntil.IsZero())
+	g.clear(key)
+	return tracked
+}
+
+// clear forgets the failures and lockout of username, keeping count of its attempts under way.
+func (g *Guard) clear(username string) {
+	entry := g.usernames[username]
+	if entry == nil {
+		return
+	}
+	if entry.pending == 0 {
+		delete(g.usernames, username)
+		return
+	}
+	*entry = attempts{pending: entry.pending, lastBegun: entry.lastBegun}
+}
+
+// current returns the entry for key, creating it if needed. The failures start over once an
+// earlier lockout has run out or the last failure is long past.
+func (g *Guard) current(entries map[string]*attempts, key string, now time.Time) *attempts {
+	entry := entries[key]
+	if entry == nil {
+		entry = &attempts{}
+		entries[key] = entry
+		return entry
+	}
+	if entry.pending > 0 && now.Sub(entry.lastBegun) > abandonAfter {
+		entry.pending = 0
+	}
+	expired := !entry.lockedUntil.IsZero() && !now.Before(entry.lockedUntil)
+	if expired || (entry.failures > 0 && now.Sub(entry.lastFailure) > g.options.LockoutDuration) {
+		*entry = attempts{pending: entry.pending, lastBegun: entry.lastBegun}
+	}
+	return entry
+}
+
+// crowded reports whether the attempts under way for an entry leave no room for another: after a
+// failure only one attempt at a time is let through, and the attempts under way may not add up to
+// a lockout.
+func (g *Guard) crowded(entry *attempts, maxFailures int) bool {
+	if entry.pending == 0 {
+		return false
+	}
+	if entry.failures > 0 && g.options.BaseDelay > 0 {
+		return true
+	}
+	return maxFailures > 0 && entry.failures+entry.pending >= maxFailures
+}
+
+// fail counts a failure for key and reports whether it just caused a lockout.
+func (g *Guard) fail(entries map[string]*attempts, key string, maxFailures int, now time.Time) bool {
+	entry := g.current(entries, key, now)
+	entry.failures++
+	entry.lastFailure = now
+
+	if maxFailures > 0 && entry.failures >= maxFailures && entry.lockedUntil.IsZero() {
+		entry.lockedUntil = now.Add(g.options.LockoutDuration)
+		return true
+	}
+	return false
+}
+
+// delay is the backoff wait after the given number of consecutive failures.
+func (g *Guard) delay(failures int) time.Duration {
+	if failures <= 0 || g.options.BaseDelay <= 0 {
+		return 0
+	}
+	wait := g.options.BaseDelay
+	for i := 1; i < failures && wait < g.options.MaxDelay; i++ {
+		wait *= 2
+	}
+	if g.options.MaxDelay > 0 && wait > g.options.MaxDelay {
+		wait = g.options.MaxDelay
+	}
+	return wait
+}
+
+// sweep forgets entries that no longer delay or lock anything, at most once a minute.
+func (g *Guard) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) < time.Minute {
+		return
+	}
+	g.lastSweep = now
+
+	idle := g.options.LockoutDuration
+	if g.options.MaxDelay > idle {
+		idle = g.options.MaxDelay
+	}
+	for _, entries := range []map[string]*attempts{g.usernames, g.ips} {
+		for key, entry := range entries {
+			if (entry.pending == 0 || now.Sub(entry.lastBegun) > abandonAfter) && now.Sub(entry.lastFailure) > idle && now.After(entry.lockedUntil) {
+				delete(entries, key)
+			}
+		}
+	}
+}
+
+func normalize(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
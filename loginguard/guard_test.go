@@ -0,0 +1,272 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testGuard returns a guard whose clock only moves when the returned advance function is called.
+func testGuard(options Options) (guard *Guard, advance func(time.Duration)) {
+	var mu sync.Mutex
+	current := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	guard = New(options)
+	guard.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return current
+	}
+	guard.lastSweep = current
+	return guard, func(step time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		current = current.Add(step)
+	}
+}
+
+// failLogin begins an attempt and settles it as failed.
+func failLogin(t *testing.T, guard *Guard, username string, ip string) {
+	t.Helper()
+	attempt, retryAfter, _ := guard.Begin(username, ip)
+	if attempt == nil {
+		t.Fatalf("Begin(%q, %q) refused the attempt, retry after %s", username, ip, retryAfter)
+	}
+	attempt.Fail(context.Background())
+}
+
+// probe reports what Begin answers for username from ip, without counting the attempt.
+func probe(guard *Guard, username string, ip string) (retryAfter time.Duration, locked bool) {
+	attempt, retryAfter, locked := guard.Begin(username, ip)
+	attempt.Release()
+	return retryAfter, locked
+}
+
+func TestDelay(t *testing.T) {
+	guard := New(Options{BaseDelay: time.Second, MaxDelay: 10 * time.Second})
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+	for _, test := range tests {
+		if got := guard.delay(test.failures); got != test.want {
+			t.Errorf("delay(%d) = %s, want %s", test.failures, got, test.want)
+		}
+	}
+
+	if got := New(Options{MaxDelay: time.Minute}).delay(3); got != 0 {
+		t.Errorf("delay without a base delay = %s, want 0", got)
+	}
+}
+
+func TestBackoffAfterFailures(t *testing.T) {
+	guard, advance := testGuard(Options{MaxUsernameFailures: 10, MaxIPFailures: 10, LockoutDuration: time.Hour, BaseDelay: time.Second, MaxDelay: time.Minute})
+
+	if wait, locked := probe(guard, "alice", "10.0.0.1"); wait != 0 || locked {
+		t.Fatalf("Begin before any failure = (%s, %v), want (0s, false)", wait, locked)
+	}
+
+	failLogin(t, guard, "alice", "10.0.0.1")
+	if wait, locked := probe(guard, "alice", "10.0.0.1"); wait != time.Second || locked {
+		t.Errorf("Begin right after a failure = (%s, %v), want (1s, false)", wait, locked)
+	}
+
+	advance(time.Second)
+	failLogin(t, guard, "alice", "10.0.0.1")
+	advance(2 * time.Second)
+	failLogin(t, guard, "alice", "10.0.0.1")
+
+	if wait, locked := probe(guard, "alice", "10.0.0.2"); wait != 4*time.Second || locked {
+		t.Errorf("Begin after 3 failures = (%s, %v), want (4s, false)", wait, locked)
+	}
+	if wait, _ := probe(guard, "bob", "10.0.0.1"); wait != 4*time.Second {
+		t.Errorf("Begin from the failing IP = %s, want 4s", wait)
+	}
+
+	advance(3 * time.Second)
+	if wait, _ := probe(guard, "alice", "10.0.0.2"); wait != time.Second {
+		t.Errorf("Begin 3s later = %s, want 1s", wait)
+	}
+
+	advance(time.Second)
+	if wait, _ := probe(guard, "alice", "10.0.0.2"); wait != 0 {
+		t.Errorf("Begin once the backoff has passed = %s, want 0s", wait)
+	}
+}
+
+func TestLockoutAfterMaxFailures(t *testing.T) {
+	guard, advance := testGuard(Options{MaxUsernameFailures: 3, MaxIPFailures: 100, LockoutDuration: 15 * time.Minute})
+
+	for i := 0; i < 3; i++ {
+		failLogin(t, guard, "Alice", "10.0.0.1")
+	}
+
+	// Usernames are matched regardless of case and surrounding spaces
+	if wait, locked := probe(guard, " alice ", "10.0.0.2"); wait != 15*time.Minute || !locked {
+		t.Fatalf("Begin after 3 failures = (%s, %v), want (15m0s, true)", wait, locked)
+	}
+
+	advance(10 * time.Minute)
+	if wait, locked := probe(guard, "alice", "10.0.0.2"); wait != 5*time.Minute || !locked {
+		t.Errorf("Begin 10m into the lockout = (%s, %v), want (5m0s, true)", wait, locked)
+	}
+
+	advance(5 * time.Minute)
+	if _, locked := probe(guard, "alice", "10.0.0.2"); locked {
+		t.Error("Begin still reports a lockout after it ran out")
+	}
+
+	// The count starts over once the lockout has run out
+	failLogin(t, guard, "alice", "10.0.0.2")
+	failLogin(t, guard, "alice", "10.0.0.2")
+	if wait, locked := probe(guard, "alice", "10.0.0.3"); wait != 0 || locked {
+		t.Errorf("Begin after 2 failures past the lockout = (%s, %v), want (0s, false)", wait, locked)
+	}
+}
+
+func TestIPLockout(t *testing.T) {
+	guard, _ := testGuard(Options{MaxUsernameFailures: 100, MaxIPFailures: 3, LockoutDuration: time.Hour})
+
+	for _, username := range []string{"alice", "bob", "carol"} {
+		failLogin(t, guard, username, "10.0.0.1")
+	}
+
+	if wait, locked := probe(guard, "dave", "10.0.0.1"); wait != time.Hour || !locked {
+		t.Errorf("Begin from the locked IP = (%s, %v), want (1h0m0s, true)", wait, locked)
+	}
+	if wait, locked := probe(guard, "dave", "10.0.0.2"); wait != 0 || locked {
+		t.Errorf("Begin from another IP = (%s, %v), want (0s, false)", wait, locked)
+	}
+}
+
+func TestSucceedKeepsIPCount(t *testing.T) {
+	guard, advance := testGuard(Options{MaxUsernameFailures: 5, MaxIPFailures: 5, LockoutDuration: time.Hour, BaseDelay: time.Second, MaxDelay: time.Minute})
+
+	failLogin(t, guard, "alice", "10.0.0.1")
+	advance(time.Second)
+	failLogin(t, guard, "alice", "10.0.0.1")
+	advance(2 * time.Second)
+
+	attempt, _, _ := guard.Begin("alice", "10.0.0.1")
+	if attempt == nil {
+		t.Fatal("Begin refused the attempt once the backoff had passed")
+	}
+	attempt.Succeed()
+
+	if wait, _ := probe(guard, "alice", "10.0.0.2"); wait != 0 {
+		t.Errorf("Begin for the username after a success = %s, want 0s", wait)
+	}
+
+	// The IP is on its third failure, not its first
+	failLogin(t, guard, "bob", "10.0.0.1")
+	if wait, _ := probe(guard, "carol", "10.0.0.1"); wait != 4*time.Second {
+		t.Errorf("Begin from the IP after another failure = %s, want 4s", wait)
+	}
+}
+
+func TestReleaseDoesNotCount(t *testing.T) {
+	guard, _ := testGuard(Options{MaxUsernameFailures: 1, MaxIPFailures: 1, LockoutDuration: time.Hour, BaseDelay: time.Second, MaxDelay: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		attempt, _, _ := guard.Begin("alice", "10.0.0.1")
+		if attempt == nil {
+			t.Fatalf("Begin refused attempt %d after released ones", i+1)
+		}
+		attempt.Release()
+		attempt.Fail(context.Background()) // Already settled, so not counted
+	}
+
+	var unsettled *Attempt
+	unsettled.Fail(context.Background())
+	unsettled.Succeed()
+	unsettled.Release()
+}
+
+func TestBeginCountsAttemptsUnderWay(t *testing.T) {
+	const maxFailures = 5
+	guard, advance := testGuard(Options{MaxUsernameFailures: maxFailures, MaxIPFailures: 1000, LockoutDuration: time.Hour, BaseDelay: time.Second, MaxDelay: time.Minute})
+
+	// A burst of parallel guesses, none of them settled before all have begun
+	burst := func(username string, ip string) (granted []*Attempt) {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if attempt, _, _ := guard.Begin(username, ip); attempt != nil {
+					mu.Lock()
+					granted = append(granted, attempt)
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		return granted
+	}
+
+	granted := burst("alice", "10.0.0.1")
+	if len(granted) != maxFailures {
+		t.Fatalf("Begin let %d parallel attempts through without failures, want %d", len(granted), maxFailures)
+	}
+	for _, attempt := range granted {
+		attempt.Fail(context.Background())
+	}
+	if _, locked := probe(guard, "alice", "10.0.0.2"); !locked {
+		t.Error("the failed parallel attempts did not lock the account")
+	}
+
+	// Once a username has failed, only one attempt at a time is let through
+	failLogin(t, guard, "bob", "10.0.0.3")
+	advance(time.Second)
+	granted = burst("bob", "10.0.0.3")
+	if len(granted) != 1 {
+		t.Errorf("Begin let %d parallel attempts through after a failure, want 1", len(granted))
+	}
+	if wait, locked := probe(guard, "bob", "10.0.0.4"); wait != 2*time.Second || locked {
+		t.Errorf("Begin while an attempt is under way = (%s, %v), want (2s, false)", wait, locked)
+	}
+}
+
+func TestAbandonedAttemptsExpire(t *testing.T) {
+	guard, advance := testGuard(Options{MaxUsernameFailures: 1, MaxIPFailures: 100, LockoutDuration: time.Hour})
+
+	if attempt, _, _ := guard.Begin("alice", "10.0.0.1"); attempt == nil {
+		t.Fatal("Begin refused the first attempt")
+	}
+	if wait, _ := probe(guard, "alice", "10.0.0.1"); wait == 0 {
+		t.Error("Begin let a second attempt through while the only allowed one is under way")
+	}
+
+	advance(abandonAfter + time.Second)
+	if wait, locked := probe(guard, "alice", "10.0.0.1"); wait != 0 || locked {
+		t.Errorf("Begin after the attempt was abandoned = (%s, %v), want (0s, false)", wait, locked)
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	guard, _ := testGuard(Options{MaxUsernameFailures: 1, MaxIPFailures: 100, LockoutDuration: time.Hour, BaseDelay: time.Second, MaxDelay: time.Minute})
+
+	failLogin(t, guard, "alice", "10.0.0.1")
+	if _, locked := probe(guard, "alice", "10.0.0.2"); !locked {
+		t.Fatal("Begin after the only allowed failure does not report a lockout")
+	}
+
+	if !guard.Unlock("ALICE") {
+		t.Error("Unlock reported nothing to clear for a locked username")
+	}
+	if wait, locked := probe(guard, "alice", "10.0.0.2"); wait != 0 || locked {
+		t.Errorf("Begin after Unlock = (%s, %v), want (0s, false)", wait, locked)
+	}
+	if guard.Unlock("alice") {
+		t.Error("Unlock reported something to clear for an untracked username")
+	}
+}
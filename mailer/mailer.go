@@ -0,0 +1,193 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+	DriverLog  = "log"
+)
+
+// smtpTimeout bounds connecting to the SMTP server and the whole conversation with it, so a server
+// that stops answering cannot hold up the request sending the message.
+const smtpTimeout = 30 * time.Second
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages.
+type Mailer interface {
+	Send(message Message) error
+}
+
+// Config selects and configures a mailer. Driver is "smtp", "file" or "log".
+type Config struct {
+	Driver   string
+	From     string
+	Host     string // SMTP only
+	Port     int    // SMTP only
+	Username string // SMTP only; no authentication when empty (e.g. a local mail catcher)
+	Password string // SMTP only
+	FilePath string // File only
+}
+
+// New builds the mailer described by config.
+func New(config Config) (Mailer, error) {
+	switch config.Driver {
+	case DriverSMTP:
+		if config.Host == "" || config.Port <= 0 {
+			return nil, fmt.Errorf("smtp mailer needs a host and a port")
+		}
+		if config.From == "" {
+			return nil, fmt.Errorf("smtp mailer needs a sender address")
+		}
+		return &SMTPMailer{
+			Address:  net.JoinHostPort(config.Host, fmt.Sprint(config.Port)),
+			Host:     config.Host,
+			Username: config.Username,
+			Password: config.Password,
+			From:     config.From,
+		}, nil
+	case DriverFile:
+		if config.FilePath == "" {
+			return nil, fmt.Errorf("file mailer needs a file path")
+		}
+		return &FileMailer{Path: config.FilePath, From: config.From}, nil
+	case DriverLog, "":
+		return LogMailer{From: config.From}, nil
+	default:
+		return nil, fmt.Errorf("unknown mailer driver %q", config.Driver)
+	}
+}
+
+// SMTPMailer sends messages through an SMTP server.
+type SMTPMailer struct {
+	Address  string // host:port
+	Host     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers the message to the SMTP server, upgrading to TLS when the server offers it.
+func (m *SMTPMailer) Send(message Message) error {
+	if strings.ContainsAny(m.From+message.To, "\r\n") {
+		return errors.New("smtp: a sender or recipient address contains a line break")
+	}
+
+	conn, err := net.DialTimeout("tcp", m.Address, smtpTimeout)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(smtpTimeout)); err != nil {
+		conn.Close()
+		return err
+	}
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.Host}); err != nil {
+			return err
+		}
+	}
+	if m.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp: server does not support authentication")
+		}
+		if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(m.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(message.To); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(format(m.From, message)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// FileMailer appends every message to a file instead of sending it.
+type FileMailer struct {
+	Path string
+	From string
+
+	mu sync.Mutex
+}
+
+// Send appends the message to the file.
+func (m *FileMailer) Send(message Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(format(m.From, message), "\r\n"...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// LogMailer logs that a message would have been sent instead of sending it. The body is left out
+// because it can hold live tokens; use FileMailer to read messages during development.
+type LogMailer struct {
+	From string
+}
+
+// Send logs the recipient and subject of the message.
+func (m LogMailer) Send(message Message) error {
+	slog.Info("mail not sent, logged instead", "to", message.To, "subject", message.Subject)
+	return nil
+}
+
+// format renders the message as an RFC 5322 email.
+func format(from string, message Message) []byte {
+	var builder strings.Builder
+	if from != "" {
+		builder.WriteString("From: " + from + "\r\n")
+	}
+	builder.WriteString("To: " + message.To + "\r\n")
+	builder.WriteString("Subject: " + message.Subject + "\r\n")
+	builder.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	builder.WriteString("\r\n")
+	return []byte(builder.String())
+}
@@ -1,98 +1,337 @@
 package main
 
 import (
-	"database/sql"
-	"fmt"
+	"context"
+	"errors"
+	"flag"
+	"io/fs"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"sb-go-readrate-nabiel/accounts"
+	"sb-go-readrate-nabiel/charts"
+	"sb-go-readrate-nabiel/config"
 	"sb-go-readrate-nabiel/controllers"
 	"sb-go-readrate-nabiel/database"
+	"sb-go-readrate-nabiel/health"
+	"sb-go-readrate-nabiel/logging"
+	"sb-go-readrate-nabiel/loginguard"
+	"sb-go-readrate-nabiel/mailer"
+	"sb-go-readrate-nabiel/metrics"
 	"sb-go-readrate-nabiel/middleware"
+	"sb-go-readrate-nabiel/repository"
+	"sb-go-readrate-nabiel/reviewfilter"
+	"sb-go-readrate-nabiel/structs"
+	"sb-go-readrate-nabiel/tracing"
+	"strconv"
+	"syscall"
+	"time"
 
+	"github.com/XSAM/otelsql"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq" // PostgreSQL driver
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
 
 func main() {
-	// Load environment variables
-	err := godotenv.Load("config/.env")
-	if err != nil {
+	// Load variables from config/.env when it exists; variables already in the environment win
+	if err := godotenv.Load("config/.env"); err != nil && !errors.Is(err, fs.ErrNotExist) {
 		log.Fatalf("Error loading .env file: %s", err)
 	}
 
-	// Database connection string
-	dbHost := os.Getenv("PGHOST")
-	dbPort := os.Getenv("PGPORT")
-	dbUser := os.Getenv("PGUSER")
-	dbPassword := os.Getenv("PGPASSWORD")
-	dbName := os.Getenv("PGDATABASE")
+	// Configuration from the config file, environment variables and flags, in increasing precedence
+	cfg, err := config.Load(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%s", err)
+	}
 
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		dbHost, dbPort, dbUser, dbPassword, dbName)
+	// Structured logs; records logged while handling a request carry its request ID and user ID
+	if err := logging.Setup(os.Stdout, cfg.Log.Level, cfg.Log.Format); err != nil {
+		log.Fatalf("Error configuring logging: %s", err)
+	}
+	if os.Getenv(gin.EnvGinMode) == "" {
+		// Keep gin's own text output (route list, debug warnings) out of the structured logs
+		gin.SetMode(gin.ReleaseMode)
+	}
 
-	// Open database connection
-	db, err := sql.Open("postgres", connStr)
+	// Spans for requests and SQL queries, exported as configured; trace context is always propagated
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
 	if err != nil {
-		log.Fatalf("Error opening database connection: %s", err)
+		fatal("error configuring tracing", err)
+	}
+
+	// Open database connection; every query becomes a span under the span of the request making it
+	db, err := otelsql.Open("postgres", cfg.Database.DataSource(),
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitConnPrepare:      true,
+			OmitRows:             true,
+			DisableErrSkip:       true,
+		}),
+	)
+	if err != nil {
+		fatal("error opening database connection", err)
 	}
-	defer func() {
-		if cerr := db.Close(); cerr != nil {
-			log.Printf("Error closing database connection: %s", cerr)
-		}
-	}()
 
 	// Ping database to verify connection
 	err = db.Ping()
 	if err != nil {
-		log.Fatalf("Error connecting to the database: %s", err)
+		fatal("error connecting to the database", err)
 	}
-	fmt.Println("Successfully connected to the database!")
+	slog.Info("connected to the database")
+
+	// Export connection pool statistics on /metrics
+	metrics.RegisterDatabase(db, "readrate")
 
 	// Run database migrations
 	database.DBMigrate(db)
 
+	// Queries running longer than this are canceled and answered with a 504
+	repository.QueryTimeout = cfg.Database.QueryTimeout
+	repository.JobTimeout = cfg.Database.JobTimeout
+
+	// Start the chart refresher (top-rated and trending charts are materialized on a schedule)
+	stopCharts := charts.StartScheduler(context.Background(), db, cfg.Charts)
+
+	// Erase accounts whose deletion grace period has passed
+	controllers.AccountDeletionGracePeriod = cfg.Accounts.DeletionGracePeriod
+	stopPurge := accounts.StartPurgeScheduler(context.Background(), db, cfg.Accounts.PurgeInterval)
+
+	// Reviews are hidden automatically after this many open reports (0 disables it)
+	controllers.ReportHideThreshold = cfg.Reviews.ReportHideThreshold
+
+	// Rating scale (half stars from 0.5 to 5 by default)
+	controllers.RatingScale = cfg.Rating
+
+	// Load the review content filter rules
+	reviewFilter, err := reviewfilter.LoadFile(cfg.Reviews.FilterConfig)
+	if err != nil {
+		fatal("error loading review filter rules", err)
+	}
+	controllers.ReviewFilter = reviewFilter
+
+	// Username and password rules
+	credentialPolicy, err := cfg.CredentialPolicy()
+	if err != nil {
+		fatal("invalid credential policy", err)
+	}
+	controllers.CredentialPolicy = credentialPolicy
+
+	// Mailer for password reset and email verification messages ("smtp", "file" or "log")
+	accountMailer, err := mailer.New(cfg.Mail)
+	if err != nil {
+		fatal("error configuring mailer", err)
+	}
+	controllers.Mailer = accountMailer
+	controllers.PasswordResetTokenTTL = cfg.Tokens.PasswordResetTTL
+	controllers.EmailVerificationTokenTTL = cfg.Tokens.EmailVerificationTTL
+	controllers.SessionTTL = cfg.Tokens.SessionTTL
+
+	// Failed-login throttling: exponential backoff, then a temporary lockout per username and client IP
+	loginGuard := loginguard.New(cfg.Login)
+	controllers.LoginGuard = loginGuard
+
+	// Dependencies /readyz verifies
+	controllers.ReadinessChecks = []health.Check{health.Database(db), health.Migrations(db)}
+	if cfg.Mail.Driver == mailer.DriverFile {
+		controllers.ReadinessChecks = append(controllers.ReadinessChecks, health.WritableFile("mail_file", cfg.Mail.FilePath))
+	}
+
 	// Initialize Gin router
-	router := gin.Default()
+	router := gin.New()
+	// Client IPs, which login throttling is keyed on, only come from forwarding headers set by a trusted proxy
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		fatal("invalid trusted proxies", err)
+	}
+	router.Use(middleware.Tracing(cfg.Tracing.ServiceName), middleware.RequestLogger(), middleware.Recover(), middleware.Metrics())
+
+	// Probes and build information
+	router.GET("/healthz", controllers.HandleHealthz) // Liveness
+	router.GET("/readyz", controllers.HandleReadyz)   // Readiness: database, migrations, storage
+	router.GET("/version", controllers.HandleVersion)
+	router.GET("/metrics", gin.WrapH(metrics.Handler())) // Prometheus exposition format
 
 	// Public routes
-	router.POST("/register", controllers.HandleRegisterUser) // Register new user
+	router.POST("/register", controllers.HandleRegisterUser)                         // Register new user
+	router.POST("/login", middleware.Login(db, loginGuard), controllers.HandleLogin) // Password and second factor once, for a session token
+	router.POST("/verify-email", controllers.HandleVerifyEmail)
+	router.POST("/password-reset", controllers.HandleRequestPasswordReset)         // Email a password reset token
+	router.POST("/password-reset/confirm", controllers.HandleConfirmPasswordReset) // Set a new password with the token
+	router.GET("/users/:id", controllers.HandleGetUserProfile)                     // Public profile
 	router.GET("/books", controllers.HandleFindBooks)
 	router.GET("/books/:id", controllers.HandleFindBook)
 	router.GET("/categories", controllers.HandleFindCategories)
 	router.GET("/categories/:id", controllers.HandleFindCategory)
 	router.GET("/categories/:id/books", controllers.HandleFindBooksByCategory)
-	router.GET("/books/:id/reviews", controllers.HandleGetReviewsForBook) // Get reviews for a specific book
+	router.GET("/books/:id/reviews", controllers.HandleGetReviewsForBook)    // Get reviews for a specific book
+	router.GET("/books/:id/ratings", controllers.HandleGetBookRatingSummary) // Overall and per-aspect rating averages
+	router.GET("/aspects", controllers.HandleFindRatingAspects)
+	router.GET("/categories/:id/aspects", controllers.HandleFindCategoryAspects)
+	router.GET("/reviews/:id/comments", controllers.HandleGetReviewComments)   // Get comments (with replies) on a review
+	router.GET("/reviews/:id/revisions", controllers.HandleGetReviewRevisions) // Get earlier versions of a review
+	router.GET("/charts/top-rated", controllers.HandleGetTopRatedChart)
+	router.GET("/charts/trending", controllers.HandleGetTrendingChart)
+	router.GET("/categories/:id/charts/top-rated", controllers.HandleGetCategoryTopRatedChart)
+	router.GET("/categories/:id/charts/trending", controllers.HandleGetCategoryTrendingChart)
 
 	// Authenticated routes
 	authenticated := router.Group("/")
-	authenticated.Use(middleware.Authenticate(db)) // Apply authentication middleware
+	authenticated.Use(middleware.Authenticate(db, loginGuard)) // Apply authentication middleware
+
+	{
+		// Profile routes
+		authenticated.GET("/me", controllers.HandleGetMe)
+		authenticated.PATCH("/me", controllers.HandleUpdateMe)
+		authenticated.DELETE("/me", controllers.HandleDeleteMe)                            // Schedule account deletion
+		authenticated.POST("/me/cancel-deletion", controllers.HandleCancelAccountDeletion) // Keep the account after all
+		authenticated.GET("/me/export", controllers.HandleExportMe)                        // ZIP of own data as JSON and CSV
+		authenticated.POST("/logout", controllers.HandleLogout)                            // End the current session
+		authenticated.POST("/me/password", controllers.HandleChangePassword)
+		authenticated.POST("/me/email/verification", controllers.HandleResendVerificationEmail) // Resend the verification email
+		authenticated.POST("/me/2fa", controllers.HandleEnrollTwoFactor)                        // Start TOTP enrollment
+		authenticated.POST("/me/2fa/confirm", controllers.HandleConfirmTwoFactor)               // Enable 2FA with a first code
+		authenticated.POST("/me/2fa/recovery-codes", controllers.HandleRegenerateRecoveryCodes)
+		authenticated.DELETE("/me/2fa", controllers.HandleDisableTwoFactor)
+		authenticated.GET("/me/api-keys", controllers.HandleGetAPIKeys)
+		authenticated.POST("/me/api-keys", controllers.HandleCreateAPIKey)
+		authenticated.DELETE("/me/api-keys/:id", controllers.HandleRevokeAPIKey)
+
+		// Review routes (NEW)
+		authenticated.POST("/books/:book_id/reviews", controllers.HandleCreateReview)  // Create review for a book
+		authenticated.PUT("/reviews/:id", controllers.HandleUpdateReview)              // Update own review
+		authenticated.DELETE("/reviews/:id", controllers.HandleDeleteReview)           // Delete own review
+		authenticated.GET("/users/:id/reviews", controllers.HandleGetReviewsByUser)    // Get reviews by a specific user, as far as their privacy settings allow
+		authenticated.POST("/reviews/:id/votes", controllers.HandleVoteReview)         // Vote a review helpful or unhelpful
+		authenticated.DELETE("/reviews/:id/votes", controllers.HandleDeleteReviewVote) // Withdraw own vote
+
+		// Review comment routes
+		authenticated.POST("/reviews/:id/comments", controllers.HandleCreateReviewComment) // Comment on a review or reply to a comment
+		authenticated.PUT("/comments/:id", controllers.HandleUpdateReviewComment)          // Update own comment
+		authenticated.DELETE("/comments/:id", controllers.HandleDeleteReviewComment)       // Delete own comment
+
+		// Review reports
+		authenticated.POST("/reviews/:id/reports", controllers.HandleReportReview) // Report a review
+	}
+
+	// Catalog routes (editors and admins only)
+	catalog := router.Group("/")
+	catalog.Use(middleware.Authenticate(db, loginGuard), middleware.RequireRole(structs.RoleEditor, structs.RoleAdmin))
 
 	{
 		// Book routes
-		authenticated.POST("/books", controllers.HandleCreateBook)
-		authenticated.PUT("/books/:id", controllers.HandleUpdateBook)
-		authenticated.DELETE("/books/:id", controllers.HandleDeleteBook)
+		catalog.POST("/books", controllers.HandleCreateBook)
+		catalog.PUT("/books/:id", controllers.HandleUpdateBook)
+		catalog.DELETE("/books/:id", controllers.HandleDeleteBook)
 
 		// Category routes
-		authenticated.POST("/categories", controllers.HandleCreateCategory)
-		authenticated.PUT("/categories/:id", controllers.HandleUpdateCategory)
-		authenticated.DELETE("/categories/:id", controllers.HandleDeleteCategory)
+		catalog.POST("/categories", controllers.HandleCreateCategory)
+		catalog.PUT("/categories/:id", controllers.HandleUpdateCategory)
+		catalog.DELETE("/categories/:id", controllers.HandleDeleteCategory)
+		catalog.PUT("/categories/:id/aspects", controllers.HandleUpdateCategoryAspects)
 
-		// Review routes (NEW)
-		authenticated.POST("/books/:book_id/reviews", controllers.HandleCreateReview) // Create review for a book
-		authenticated.PUT("/reviews/:id", controllers.HandleUpdateReview)           // Update own review
-		authenticated.DELETE("/reviews/:id", controllers.HandleDeleteReview)         // Delete own review
-		authenticated.GET("/users/:user_id/reviews", controllers.HandleGetReviewsByUser) // Get reviews by a specific user (can be secured further if needed)
+		// Rating aspect routes
+		catalog.POST("/aspects", controllers.HandleCreateRatingAspect)
+	}
+
+	// Moderation routes (moderators and admins only)
+	moderation := router.Group("/moderation")
+	moderation.Use(middleware.Authenticate(db, loginGuard), middleware.RequireRole(structs.RoleModerator, structs.RoleAdmin))
+
+	{
+		moderation.GET("/reviews", controllers.HandleGetModerationQueue)
+		moderation.POST("/reviews/:id/approve", controllers.HandleApproveReview)
+		moderation.POST("/reviews/:id/hide", controllers.HandleHideReview)
+		moderation.DELETE("/reviews/:id", controllers.HandleModeratorDeleteReview)
+	}
+
+	// Admin routes (admins only)
+	admin := router.Group("/admin")
+	admin.Use(middleware.Authenticate(db, loginGuard), middleware.RequireRole(structs.RoleAdmin))
+
+	{
+		admin.GET("/users", controllers.HandleGetUsers) // ?q=, ?role=, ?status=active|suspended
+		admin.GET("/users/:id", controllers.HandleGetUser)
+		admin.GET("/users/:id/activity", controllers.HandleGetUserActivity)
+		admin.POST("/users/:id/suspend", controllers.HandleSuspendUser)
+		admin.POST("/users/:id/unsuspend", controllers.HandleUnsuspendUser)
+		admin.POST("/users/:id/password-reset", controllers.HandleForcePasswordReset)
+		admin.PUT("/users/:id/role", controllers.HandleChangeUserRole)
+		admin.DELETE("/users/:id", controllers.HandleAdminDeleteUser)
+		admin.POST("/users/:id/unlock", controllers.HandleUnlockUser) // Lift a login lockout
+		admin.GET("/actions", controllers.HandleGetAdminActions)      // Audit log of admin actions
+		admin.GET("/roles", controllers.HandleGetRoleSettings)
+		admin.PUT("/roles/:role", controllers.HandleUpdateRoleSettings) // e.g. require 2FA for editors
 	}
 
 	// Run the server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080" // Default port
+	server := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Port),
+		Handler:           router,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	// Shut down on SIGINT or SIGTERM
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		slog.Info("server starting", "port", cfg.Port)
+		serverErrors <- server.ListenAndServe()
+	}()
+
+	failed := false
+	select {
+	case err := <-serverErrors:
+		slog.Error("server failed", "error", err)
+		failed = true
+	case <-signals.Done():
+		slog.Info("shutting down, waiting for in-flight requests", "grace_period", cfg.Server.ShutdownGracePeriod.String())
 	}
-	log.Printf("Server starting on port %s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Server failed to start: %s", err)
+	// A second signal terminates the process right away
+	stopSignals()
+
+	// Stop accepting connections and let in-flight requests finish within the grace period
+	shutdownContext, cancelShutdown := context.WithTimeout(context.Background(), cfg.Server.ShutdownGracePeriod)
+	defer cancelShutdown()
+	if err := server.Shutdown(shutdownContext); err != nil {
+		slog.Warn("grace period over, closing remaining connections", "error", err)
+		server.Close()
 	}
-}
\ No newline at end of file
+
+	// Cancel the background workers and wait for them to return before the database goes away
+	stopCharts()
+	stopPurge()
+
+	if err := database.DbConnection.Close(); err != nil {
+		slog.Error("error closing database connection", "error", err)
+	}
+
+	// Flush the spans still buffered
+	flushContext, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := shutdownTracing(flushContext); err != nil {
+		slog.Error("error flushing traces", "error", err)
+	}
+	slog.Info("server stopped")
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// fatal logs err and exits.
+func fatal(message string, err error) {
+	slog.Error(message, "error", err)
+	os.Exit(1)
+}
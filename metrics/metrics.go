@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "readrate"
+
+// Registry holds every metric served on /metrics.
+var Registry = prometheus.NewRegistry()
+
+// HTTP metrics, labelled with the route template (e.g. "/books/:id") rather than the raw path so
+// the number of series stays bounded.
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to handle HTTP requests, by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	HTTPInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being handled.",
+	})
+)
+
+// BcryptDuration measures password comparisons during Basic Auth, by whether the password matched.
+var BcryptDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "auth_bcrypt_duration_seconds",
+	Help:      "Time taken to compare a password with its bcrypt hash during authentication.",
+	Buckets:   []float64{0.01, 0.025, 0.05, 0.1, 0.2, 0.4, 0.8, 1.6},
+}, []string{"result"})
+
+// Domain counters.
+var (
+	UsersRegistered = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "users_registered_total",
+		Help:      "Users registered.",
+	})
+
+	BooksCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "books_created_total",
+		Help:      "Books created.",
+	})
+
+	ReviewsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reviews_created_total",
+		Help:      "Reviews created.",
+	})
+
+	CommentsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "review_comments_created_total",
+		Help:      "Comments and replies posted on reviews.",
+	})
+
+	ReviewsReported = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "review_reports_total",
+		Help:      "Reports filed against reviews by users.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests, HTTPRequestDuration, HTTPInFlight,
+		BcryptDuration,
+		UsersRegistered, BooksCreated, ReviewsCreated, CommentsCreated, ReviewsReported,
+	)
+}
+
+// RegisterDatabase exports the connection pool statistics of db: open, in-use and idle
+// connections, and how often and how long callers waited for one.
+func RegisterDatabase(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveBcrypt records how long a password comparison took.
+func ObserveBcrypt(started time.Time, matched bool) {
+	result := "mismatch"
+	if matched {
+		result = "match"
+	}
+	BcryptDuration.WithLabelValues(result).Observe(time.Since(started).Seconds())
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
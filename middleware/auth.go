@@ -2,49 +2,304 @@ package middleware
 
 import (
 	"database/sql"
+	"math"
 	"net/http"
+	"sb-go-readrate-nabiel/logging"
+	"sb-go-readrate-nabiel/loginguard"
+	"sb-go-readrate-nabiel/metrics"
+	"sb-go-readrate-nabiel/repository"
 	"sb-go-readrate-nabiel/structs"
+	"sb-go-readrate-nabiel/totp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt" // Import bcrypt
 )
 
-func Authenticate(dbs *sql.DB) gin.HandlerFunc {
+// Authenticate checks an API key in the X-API-Key header, a session token from Login sent as
+// "Authorization: Bearer <token>", or else Basic Auth credentials. Users with two-factor
+// authentication cannot use Basic Auth here; they sign in once through Login and use the session
+// token. When guard is not nil, failed logins are throttled per username and client IP before any
+// password is compared.
+func Authenticate(dbs *sql.DB, guard *loginguard.Guard) gin.HandlerFunc {
 	return func(context *gin.Context) {
-		username, password, present := context.Request.BasicAuth()
+		if apiKey := context.GetHeader("X-API-Key"); apiKey != "" {
+			authenticateAPIKey(context, dbs, apiKey)
+			return
+		}
+		if token, found := strings.CutPrefix(context.GetHeader("Authorization"), "Bearer "); found {
+			authenticateSession(context, dbs, strings.TrimSpace(token))
+			return
+		}
 
-		if !present {
+		attempt, ok := checkPassword(context, dbs, guard)
+		if !ok {
+			return
+		}
+
+		if attempt.account.TwoFactorEnabled {
+			attempt.reservation.Release()
 			context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"detail": "Authentication required",
+				"detail": "Two-factor authentication is enabled; sign in through POST /login with a code in the X-OTP header and send the returned token as \"Authorization: Bearer <token>\"",
 			})
 			return
 		}
 
-		var account structs.User
-		// Select the hashed password
-		statement := "SELECT id, username, password FROM users WHERE username = $1"
-		err := dbs.QueryRow(statement, username).Scan(&account.ID, &account.Username, &account.Password)
+		attempt.reservation.Succeed()
+		signIn(context, attempt.account, attempt.roleRequiresTwoFactor)
+	}
+}
 
-		if err != nil {
-			if err == sql.ErrNoRows {
-				context.JSON(http.StatusUnauthorized, gin.H{"detail": "Incorrect credentials"})
-			} else {
-				context.JSON(http.StatusInternalServerError, gin.H{"detail": "Authentication failed: " + err.Error()})
-			}
-			context.Abort()
+// Login checks Basic Auth credentials and, for users with two-factor authentication, a TOTP code
+// or an unused recovery code in the X-OTP header. A TOTP code is accepted only once. The handler
+// that follows issues the session. Failures are throttled like in Authenticate.
+func Login(dbs *sql.DB, guard *loginguard.Guard) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		attempt, ok := checkPassword(context, dbs, guard)
+		if !ok {
 			return
 		}
 
-		// Compare the provided password with the hashed password from DB
-		err = bcrypt.CompareHashAndPassword([]byte(account.Password), []byte(password))
-		if err != nil {
-			context.JSON(http.StatusUnauthorized, gin.H{"detail": "Incorrect credentials"})
-			context.Abort()
+		if attempt.account.TwoFactorEnabled && !checkSecondFactor(context, dbs, attempt) {
 			return
 		}
 
-		// Authentication successful, set the user in context
-		context.Set("user", account) // Changed "account" to "user" for consistency
+		attempt.reservation.Succeed()
+
+		// Users whose role requires two-factor authentication but who have not enrolled may still sign
+		// in; their session only reaches the enrollment endpoints
+		logging.SetUserID(context.Request.Context(), attempt.account.ID)
+		context.Set("user", attempt.account)
 		context.Next()
 	}
-}
\ No newline at end of file
+}
+
+// loginAttempt is a Basic Auth sign-in whose password has been checked. Its reservation with the
+// guard, nil without one, is still open and must be settled by the caller.
+type loginAttempt struct {
+	reservation           *loginguard.Attempt
+	account               structs.User
+	totpSecret            string
+	roleRequiresTwoFactor bool
+}
+
+// checkPassword verifies the Basic Auth credentials of the request and that no admin restriction
+// applies. Otherwise it aborts the request, settles the attempt with the guard and returns false.
+func checkPassword(context *gin.Context, dbs *sql.DB, guard *loginguard.Guard) (attempt loginAttempt, ok bool) {
+	username, password, present := context.Request.BasicAuth()
+
+	if !present {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"detail": "Authentication required",
+		})
+		return attempt, false
+	}
+
+	var reservation *loginguard.Attempt
+	if guard != nil {
+		var retryAfter time.Duration
+		var locked bool
+		if reservation, retryAfter, locked = guard.Begin(username, context.ClientIP()); reservation == nil {
+			detail := "Too many failed login attempts, try again later"
+			if locked {
+				detail = "Account temporarily locked after too many failed login attempts"
+			}
+			context.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			context.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"detail": detail,
+			})
+			return attempt, false
+		}
+	}
+
+	account, totpSecret, roleRequiresTwoFactor, problem := repository.FindLoginAccount(context.Request.Context(), dbs, username)
+	if problem.Message != "" {
+		if problem.Status == http.StatusNotFound {
+			reservation.Fail(context.Request.Context())
+			context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"detail": "Incorrect credentials"})
+		} else {
+			reservation.Release()
+			context.AbortWithStatusJSON(problem.Status, gin.H{"detail": "Authentication failed: " + problem.Message})
+		}
+		return attempt, false
+	}
+
+	// Compare the provided password with the hashed password from DB
+	compareStarted := time.Now()
+	err := bcrypt.CompareHashAndPassword([]byte(account.Password), []byte(password))
+	metrics.ObserveBcrypt(compareStarted, err == nil)
+	if err != nil {
+		reservation.Fail(context.Request.Context())
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"detail": "Incorrect credentials"})
+		return attempt, false
+	}
+
+	// Restrictions set by an admin are only revealed to someone who knows the password
+	if detail := accountRestriction(account); detail != "" {
+		reservation.Release()
+		context.AbortWithStatusJSON(http.StatusForbidden, gin.H{"detail": detail})
+		return attempt, false
+	}
+
+	return loginAttempt{
+		reservation:           reservation,
+		account:               account,
+		totpSecret:            totpSecret,
+		roleRequiresTwoFactor: roleRequiresTwoFactor,
+	}, true
+}
+
+// checkSecondFactor verifies the X-OTP header of a login: a TOTP code newer than the last one
+// accepted, or an unused recovery code. Otherwise it aborts the request, settles the attempt with
+// the guard and returns false.
+func checkSecondFactor(context *gin.Context, dbs *sql.DB, attempt loginAttempt) bool {
+	code := strings.TrimSpace(context.GetHeader("X-OTP"))
+	if code == "" {
+		attempt.reservation.Release()
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"detail": "Two-factor code required in the X-OTP header",
+		})
+		return false
+	}
+
+	var valid bool
+	var problem structs.Error
+	if counter, matched := totp.Verify(attempt.totpSecret, code, time.Now()); matched {
+		valid, problem = repository.AcceptTwoFactorCode(context.Request.Context(), dbs, attempt.account.ID, counter)
+	} else {
+		valid, problem = repository.ConsumeRecoveryCode(context.Request.Context(), dbs, attempt.account.ID, code)
+	}
+	if problem.Message != "" {
+		attempt.reservation.Release()
+		context.AbortWithStatusJSON(problem.Status, gin.H{"detail": "Authentication failed: " + problem.Message})
+		return false
+	}
+	if !valid {
+		attempt.reservation.Fail(context.Request.Context())
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"detail": "Incorrect or already used two-factor code"})
+		return false
+	}
+	return true
+}
+
+// authenticateSession lets the request through as the owner of a session token issued by Login.
+func authenticateSession(context *gin.Context, dbs *sql.DB, token string) {
+	account, roleRequiresTwoFactor, problem := repository.AuthenticateSession(context.Request.Context(), dbs, token)
+	if problem.Message != "" {
+		detail := problem.Message
+		if problem.Status >= http.StatusInternalServerError {
+			detail = "Authentication failed: " + problem.Message
+		}
+		context.AbortWithStatusJSON(problem.Status, gin.H{"detail": detail})
+		return
+	}
+
+	if detail := accountRestriction(account); detail != "" {
+		context.AbortWithStatusJSON(http.StatusForbidden, gin.H{"detail": detail})
+		return
+	}
+
+	context.Set("session_token", token)
+	signIn(context, account, roleRequiresTwoFactor)
+}
+
+// signIn lets an authenticated user through. Users whose role requires two-factor authentication
+// can only reach the enrollment endpoints until they enroll.
+func signIn(context *gin.Context, account structs.User, roleRequiresTwoFactor bool) {
+	if roleRequiresTwoFactor && !account.TwoFactorEnabled && !strings.HasPrefix(context.FullPath(), "/me/2fa") {
+		context.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"detail": "Your role requires two-factor authentication; enroll through POST /me/2fa first",
+		})
+		return
+	}
+
+	logging.SetUserID(context.Request.Context(), account.ID)
+	context.Set("user", account)
+	context.Next()
+}
+
+// authenticateAPIKey lets the request through as the key's owner if the key has the scope the
+// route needs. Keys skip two-factor authentication; they can only be created by a fully
+// authenticated user. They stop working while the owner's role requires two-factor authentication
+// the owner has not enrolled in.
+func authenticateAPIKey(context *gin.Context, dbs *sql.DB, apiKey string) {
+	account, scopes, roleRequiresTwoFactor, problem := repository.AuthenticateAPIKey(context.Request.Context(), dbs, apiKey)
+	if problem.Message != "" {
+		detail := problem.Message
+		if problem.Status >= http.StatusInternalServerError {
+			detail = "Authentication failed: " + problem.Message
+		}
+		context.AbortWithStatusJSON(problem.Status, gin.H{"detail": detail})
+		return
+	}
+
+	if detail := accountRestriction(account); detail != "" {
+		context.AbortWithStatusJSON(http.StatusForbidden, gin.H{"detail": detail})
+		return
+	}
+
+	scope, allowed := requiredScope(context.Request.Method, context.FullPath())
+	if !allowed {
+		context.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"detail": "This endpoint cannot be used with an API key",
+		})
+		return
+	}
+	if !hasScope(scopes, scope) {
+		context.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"detail": "This API key lacks the " + strconv.Quote(scope) + " scope",
+		})
+		return
+	}
+
+	signIn(context, account, roleRequiresTwoFactor)
+}
+
+// accountRestriction explains why an admin has barred the account from signing in, or returns an
+// empty string when it has not been.
+func accountRestriction(account structs.User) string {
+	if account.Suspended {
+		if account.SuspensionReason != "" {
+			return "Your account is suspended: " + account.SuspensionReason
+		}
+		return "Your account is suspended"
+	}
+	if account.PasswordResetRequired {
+		return "A password reset is required; request one through POST /password-reset"
+	}
+	return ""
+}
+
+// RequireRole only lets through authenticated users holding one of the given roles. It must run
+// after Authenticate.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		currentUser, exists := context.Get("user")
+		if !exists {
+			context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"detail": "Authentication required",
+			})
+			return
+		}
+		account, ok := currentUser.(structs.User)
+		if !ok {
+			context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"detail": "Failed to get user data from context",
+			})
+			return
+		}
+
+		for _, role := range roles {
+			if account.Role == role {
+				context.Next()
+				return
+			}
+		}
+
+		context.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"detail": "You do not have permission to access this resource",
+		})
+	}
+}
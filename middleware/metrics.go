@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"sb-go-readrate-nabiel/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// metricMethods are the request methods recorded under their own name; any other method is
+// recorded as "other" so arbitrary methods cannot create new series.
+var metricMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true, http.MethodPatch: true,
+	http.MethodDelete: true, http.MethodConnect: true, http.MethodOptions: true, http.MethodTrace: true,
+}
+
+// Metrics records the count, latency and concurrency of requests. Requests that match no route are
+// grouped under "unmatched" so arbitrary paths cannot create new series. A request whose handler
+// panics is recorded as a 500, which Recover answers it with, and the panic is passed on.
+func Metrics() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		metrics.HTTPInFlight.Inc()
+		started := time.Now()
+
+		defer func() {
+			metrics.HTTPInFlight.Dec()
+			recovered := recover()
+
+			method := context.Request.Method
+			if !metricMethods[method] {
+				method = "other"
+			}
+			route := context.FullPath()
+			if route == "" {
+				route = "unmatched"
+			}
+			status := context.Writer.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if recovered != nil {
+				status = http.StatusInternalServerError
+			}
+			labels := []string{method, route, strconv.Itoa(status)}
+			metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+			metrics.HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(started).Seconds())
+
+			if recovered != nil {
+				panic(recovered)
+			}
+		}()
+
+		context.Next()
+	}
+}
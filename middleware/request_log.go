@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"sb-go-readrate-nabiel/logging"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestLogger gives every request an ID, taken from an incoming X-Request-ID header when it is
+// well formed and generated otherwise, and logs the request once it is done. The ID is sent back in
+// the X-Request-ID header and added to JSON error bodies as "request_id", and every record logged
+// with the request context carries it.
+func RequestLogger() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		started := time.Now()
+
+		requestID := context.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		context.Header(RequestIDHeader, requestID)
+		context.Request = context.Request.WithContext(logging.NewContext(context.Request.Context(), requestID))
+		writer := &errorBodyWriter{ResponseWriter: context.Writer, requestID: requestID}
+		context.Writer = writer
+
+		context.Next()
+
+		status := context.Writer.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		} else if status >= http.StatusBadRequest {
+			level = slog.LevelWarn
+		}
+
+		attributes := []slog.Attr{
+			slog.String("method", context.Request.Method),
+			slog.String("route", context.FullPath()),
+			slog.String("path", context.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(started).Microseconds())/1000),
+			slog.String("client_ip", context.ClientIP()),
+			slog.Int("bytes", context.Writer.Size()),
+		}
+		// The detail of an error response, e.g. the database error behind a 500
+		if writer.detail != "" {
+			attributes = append(attributes, slog.String("detail", writer.detail))
+		}
+		slog.LogAttrs(context.Request.Context(), level, "request", attributes...)
+	}
+}
+
+// Recover turns a panic in a handler into a 500 response and logs it with its stack trace. It
+// must run after RequestLogger so the record carries the request ID.
+func Recover() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				slog.ErrorContext(context.Request.Context(), "panic while handling request",
+					"panic", recovered, "stack", string(debug.Stack()))
+				context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+					"detail": "Internal server error",
+				})
+			}
+		}()
+		context.Next()
+	}
+}
+
+// errorBodyWriter adds the request ID to JSON object bodies of error responses and remembers their
+// "detail" for the request log.
+type errorBodyWriter struct {
+	gin.ResponseWriter
+	requestID string
+	written   bool
+	detail    string
+}
+
+func (w *errorBodyWriter) Write(data []byte) (int, error) {
+	if w.written || w.Status() < http.StatusBadRequest || !bytes.HasPrefix(data, []byte("{")) {
+		w.written = true
+		return w.ResponseWriter.Write(data)
+	}
+	w.written = true
+
+	var body struct {
+		Detail string `json:"detail"`
+	}
+	if json.Unmarshal(data, &body) != nil {
+		return w.ResponseWriter.Write(data)
+	}
+	w.detail = body.Detail
+
+	field := `"request_id":` + strconv.Quote(w.requestID)
+	if !bytes.HasPrefix(bytes.TrimSpace(data[1:]), []byte("}")) {
+		field += ","
+	}
+	if _, err := w.ResponseWriter.Write(append([]byte("{"+field), data[1:]...)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (w *errorBodyWriter) WriteString(data string) (int, error) {
+	return w.Write([]byte(data))
+}
+
+// validRequestID accepts IDs of up to 128 letters, digits, dashes, underscores and dots, so an
+// incoming header cannot inject anything into logs or responses.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	random := make([]byte, 16)
+	rand.Read(random)
+	return hex.EncodeToString(random)
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"sb-go-readrate-nabiel/structs"
+	"slices"
+	"strings"
+)
+
+// requiredScope returns the API key scope needed for a route, identified by its method and path
+// template. Routes not listed here (account security under /me/, moderation, admin) cannot be used
+// with an API key at all.
+func requiredScope(method string, route string) (scope string, allowed bool) {
+	if strings.HasPrefix(route, "/me/") || strings.HasPrefix(route, "/moderation") || strings.HasPrefix(route, "/admin") {
+		return "", false
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return structs.ScopeRead, true
+	}
+
+	switch route {
+	case "/books/:book_id/reviews", "/reviews/:id", "/reviews/:id/votes", "/reviews/:id/comments",
+		"/reviews/:id/reports", "/comments/:id":
+		return structs.ScopeReviews, true
+	case "/books", "/books/:id", "/categories", "/categories/:id", "/categories/:id/aspects", "/aspects":
+		return structs.ScopeCatalogWrite, true
+	}
+	return "", false
+}
+
+// hasScope reports whether a key with the given scopes may use a route needing scope. Every key
+// can read.
+func hasScope(scopes []string, scope string) bool {
+	return scope == structs.ScopeRead || slices.Contains(scopes, scope)
+}
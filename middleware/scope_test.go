@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"sb-go-readrate-nabiel/structs"
+	"testing"
+)
+
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		route       string
+		wantScope   string
+		wantAllowed bool
+	}{
+		{"account security read", http.MethodGet, "/me/api-keys", "", false},
+		{"account security write", http.MethodPost, "/me/2fa", "", false},
+		{"password change", http.MethodPut, "/me/password", "", false},
+		{"admin read", http.MethodGet, "/admin/users", "", false},
+		{"admin write", http.MethodPost, "/admin/users/:id/suspend", "", false},
+		{"moderation read", http.MethodGet, "/moderation/reviews", "", false},
+		{"moderation write", http.MethodPost, "/moderation/reviews/:id/hide", "", false},
+		{"own profile read", http.MethodGet, "/me", structs.ScopeRead, true},
+		{"own profile write", http.MethodPatch, "/me", "", false},
+		{"book list", http.MethodGet, "/books", structs.ScopeRead, true},
+		{"head request", http.MethodHead, "/books/:id", structs.ScopeRead, true},
+		{"unknown route read", http.MethodGet, "/unknown", structs.ScopeRead, true},
+		{"review create", http.MethodPost, "/books/:book_id/reviews", structs.ScopeReviews, true},
+		{"review update", http.MethodPut, "/reviews/:id", structs.ScopeReviews, true},
+		{"review vote", http.MethodPut, "/reviews/:id/votes", structs.ScopeReviews, true},
+		{"comment delete", http.MethodDelete, "/comments/:id", structs.ScopeReviews, true},
+		{"book create", http.MethodPost, "/books", structs.ScopeCatalogWrite, true},
+		{"category delete", http.MethodDelete, "/categories/:id", structs.ScopeCatalogWrite, true},
+		{"aspect create", http.MethodPost, "/aspects", structs.ScopeCatalogWrite, true},
+		{"unknown route write", http.MethodPost, "/unknown", "", false},
+		{"unmatched route write", http.MethodDelete, "", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scope, allowed := requiredScope(test.method, test.route)
+			if scope != test.wantScope || allowed != test.wantAllowed {
+				t.Errorf("requiredScope(%s, %q) = (%q, %v), want (%q, %v)", test.method, test.route, scope, allowed, test.wantScope, test.wantAllowed)
+			}
+		})
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{nil, structs.ScopeRead, true},
+		{nil, structs.ScopeReviews, false},
+		{[]string{structs.ScopeReviews}, structs.ScopeReviews, true},
+		{[]string{structs.ScopeReviews}, structs.ScopeCatalogWrite, false},
+	}
+	for _, test := range tests {
+		if got := hasScope(test.scopes, test.scope); got != test.want {
+			t.Errorf("hasScope(%q, %q) = %v, want %v", test.scopes, test.scope, got, test.want)
+		}
+	}
+}
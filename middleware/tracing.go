@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// Paths polled by probes and the metrics scraper, which would only add noise to the traces.
+var untracedPaths = []string{"/healthz", "/readyz", "/metrics"}
+
+// Tracing starts a span for every request, continuing the trace named by an incoming W3C
+// traceparent header. Spans are named after the route template, like the metrics.
+func Tracing(service string) gin.HandlerFunc {
+	return otelgin.Middleware(service, otelgin.WithFilter(func(request *http.Request) bool {
+		return !slices.Contains(untracedPaths, request.URL.Path)
+	}))
+}
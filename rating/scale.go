@@ -0,0 +1,59 @@
+package rating
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// StepGranularity is the finest step the database accepts (reviews store ratings in 0.5 steps).
+const StepGranularity = 0.5
+
+// MaxRating is the largest rating the database can store (rating columns are NUMERIC(3,1)).
+const MaxRating = 99.5
+
+// Scale is the range of accepted ratings and the step between them, e.g. 0.5 to 5 in 0.5 steps.
+type Scale struct {
+	Min  float64
+	Max  float64
+	Step float64
+}
+
+// DefaultScale allows half stars from 0.5 to 5.
+func DefaultScale() Scale {
+	return Scale{Min: 0.5, Max: 5, Step: 0.5}
+}
+
+// Check reports whether the scale itself is usable.
+func (scale Scale) Check() error {
+	if scale.Step <= 0 || !isMultiple(scale.Step, StepGranularity) {
+		return fmt.Errorf("rating step must be a positive multiple of %s", format(StepGranularity))
+	}
+	if scale.Min <= 0 || !isMultiple(scale.Min, StepGranularity) {
+		return fmt.Errorf("minimum rating must be a positive multiple of %s", format(StepGranularity))
+	}
+	if scale.Max <= scale.Min || !isMultiple(scale.Max-scale.Min, scale.Step) {
+		return fmt.Errorf("maximum rating must be above the minimum and reachable in steps of %s", format(scale.Step))
+	}
+	if scale.Max > MaxRating {
+		return fmt.Errorf("maximum rating cannot be above %s", format(MaxRating))
+	}
+	return nil
+}
+
+// Validate reports why value is not an accepted rating, or nil when it is.
+func (scale Scale) Validate(value float64) error {
+	if value < scale.Min || value > scale.Max || !isMultiple(value-scale.Min, scale.Step) {
+		return fmt.Errorf("must be between %s and %s in steps of %s", format(scale.Min), format(scale.Max), format(scale.Step))
+	}
+	return nil
+}
+
+func isMultiple(value float64, step float64) bool {
+	quotient := value / step
+	return math.Abs(quotient-math.Round(quotient)) < 1e-9
+}
+
+func format(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,67 @@
+package rating
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScaleCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		scale   Scale
+		wantErr bool
+	}{
+		{"default", DefaultScale(), false},
+		{"whole stars", Scale{Min: 1, Max: 5, Step: 1}, false},
+		{"largest storable maximum", Scale{Min: 0.5, Max: 99.5, Step: 0.5}, false},
+		{"maximum over the column size", Scale{Min: 1, Max: 100, Step: 1}, true},
+		{"zero step", Scale{Min: 1, Max: 5, Step: 0}, true},
+		{"step finer than the database", Scale{Min: 1, Max: 5, Step: 0.25}, true},
+		{"zero minimum", Scale{Min: 0, Max: 5, Step: 0.5}, true},
+		{"minimum off the granularity", Scale{Min: 0.3, Max: 5, Step: 0.5}, true},
+		{"maximum equal to the minimum", Scale{Min: 5, Max: 5, Step: 0.5}, true},
+		{"maximum not reachable in steps", Scale{Min: 1, Max: 5.5, Step: 1}, true},
+		{"infinite maximum", Scale{Min: 1, Max: math.Inf(1), Step: 1}, true},
+		{"step that is not a number", Scale{Min: 1, Max: 5, Step: math.NaN()}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.scale.Check(); (err != nil) != test.wantErr {
+				t.Errorf("Check() = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestScaleValidate(t *testing.T) {
+	halfStars := DefaultScale()
+	wholeStars := Scale{Min: 1, Max: 10, Step: 1}
+
+	tests := []struct {
+		name    string
+		scale   Scale
+		value   float64
+		wantErr bool
+	}{
+		{"minimum", halfStars, 0.5, false},
+		{"maximum", halfStars, 5, false},
+		{"half step", halfStars, 3.5, false},
+		{"below the minimum", halfStars, 0, true},
+		{"above the maximum", halfStars, 5.5, true},
+		{"between steps", halfStars, 3.2, true},
+		{"whole step", wholeStars, 7, false},
+		{"half step on a whole-step scale", wholeStars, 7.5, true},
+		{"not a number", halfStars, math.NaN(), true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.scale.Validate(test.value); (err != nil) != test.wantErr {
+				t.Errorf("Validate(%v) = %v, want error %v", test.value, err, test.wantErr)
+			}
+		})
+	}
+
+	if err := halfStars.Validate(6); err == nil || err.Error() != "must be between 0.5 and 5 in steps of 0.5" {
+		t.Errorf("Validate(6) = %v, want the accepted range", err)
+	}
+}
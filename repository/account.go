@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sb-go-readrate-nabiel/structs"
+	"time"
+)
+
+// ScheduleAccountDeletion marks a user's account for erasure at the given time, remembering
+// whether their reviews are to be deleted or anonymized.
+func ScheduleAccountDeletion(ctx context.Context, db *sql.DB, userID int, reviewMode string, erasedAt time.Time) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryUpdate := `UPDATE users SET deletion_scheduled_for = $1, deletion_review_mode = $2, modified_by = $3, modified_at = NOW()
+                    WHERE id = $3`
+
+	result, errExec := db.ExecContext(ctx, queryUpdate, erasedAt, reviewMode, userID)
+	if errExec != nil {
+		return structs.Error{
+			Message: errExec.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return structs.Error{
+			Message: fmt.Sprintf("user with identifier %d not found", userID),
+			Status:  http.StatusNotFound,
+		}
+	}
+	return
+}
+
+// CancelAccountDeletion withdraws a pending deletion request.
+func CancelAccountDeletion(ctx context.Context, db *sql.DB, userID int) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryUpdate := `UPDATE users SET deletion_scheduled_for = NULL, deletion_review_mode = NULL, modified_by = $1, modified_at = NOW()
+                    WHERE id = $1 AND deletion_scheduled_for IS NOT NULL`
+
+	result, errExec := db.ExecContext(ctx, queryUpdate, userID)
+	if errExec != nil {
+		return structs.Error{
+			Message: errExec.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return structs.Error{
+			Message: "no account deletion is pending",
+			Status:  http.StatusConflict,
+		}
+	}
+	return
+}
+
+// EraseAccount deletes a user and everything tied to their account. Their reviews are deleted as
+// well, or kept without an author when reviewMode is "anonymize". Their comments that other users
+// replied to are kept without an author and with "[deleted]" as the text.
+func EraseAccount(ctx context.Context, db *sql.DB, userID int, reviewMode string) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	return inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		return eraseAccount(ctx, transaction, userID, reviewMode)
+	})
+}
+
+// eraseAccount does the work of EraseAccount inside an open transaction.
+func eraseAccount(ctx context.Context, transaction *sql.Tx, userID int, reviewMode string) (problem structs.Error) {
+	if reviewMode == structs.ReviewsAnonymize {
+		// Detach the reviews first so the cascade from users does not remove them
+		queryDetach := "UPDATE reviews SET user_id = NULL, created_by = 0, modified_by = 0 WHERE user_id = $1"
+		if _, errExec := transaction.ExecContext(ctx, queryDetach, userID); errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		if _, errExec := transaction.ExecContext(ctx, "UPDATE review_revisions SET created_by = 0 WHERE created_by = $1", userID); errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+	}
+
+	// Keep the comments other users replied to, without their author or text, so the cascade from
+	// users does not take the replies with them
+	queryDetachComments := `UPDATE review_comments c SET user_id = NULL, body = '[deleted]', created_by = 0, modified_by = 0
+                            WHERE c.user_id = $1 AND EXISTS (SELECT 1 FROM review_comments r WHERE r.parent_id = c.id AND r.user_id IS DISTINCT FROM $1)`
+	if _, errExec := transaction.ExecContext(ctx, queryDetachComments, userID); errExec != nil {
+		return structs.Error{
+			Message: errExec.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	// Votes, remaining comments, reports, tokens, recovery codes, API keys and remaining reviews cascade
+	result, errExec := transaction.ExecContext(ctx, "DELETE FROM users WHERE id = $1", userID)
+	if errExec != nil {
+		return structs.Error{
+			Message: errExec.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return structs.Error{
+			Message: fmt.Sprintf("user with identifier %d not found", userID),
+			Status:  http.StatusNotFound,
+		}
+	}
+	return
+}
+
+// PurgeDueAccounts erases every account whose deletion grace period has passed and returns how
+// many were erased. Each account is erased in its own transaction, under its own QueryTimeout.
+func PurgeDueAccounts(ctx context.Context, db *sql.DB) (erased int, problem structs.Error) {
+	dueAccounts, problem := retrieveDueAccounts(ctx, db)
+	if problem.Message != "" {
+		return 0, problem
+	}
+
+	for _, account := range dueAccounts {
+		eraseError := EraseAccount(ctx, db, account.userID, account.reviewMode)
+		if eraseError.Message == "" {
+			erased++
+		} else if eraseError.Status != http.StatusNotFound {
+			return erased, eraseError
+		}
+	}
+	return erased, structs.Error{}
+}
+
+// dueAccount is an account waiting to be purged, with the review mode chosen at deletion.
+type dueAccount struct {
+	userID     int
+	reviewMode string
+}
+
+// retrieveDueAccounts lists the accounts whose deletion grace period has passed.
+func retrieveDueAccounts(ctx context.Context, db *sql.DB) (dueAccounts []dueAccount, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryDue := "SELECT id, deletion_review_mode FROM users WHERE deletion_scheduled_for <= NOW()"
+
+	rowsData, errQuery := db.QueryContext(ctx, queryDue)
+	if errQuery != nil {
+		return nil, structs.Error{
+			Message: errQuery.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	defer rowsData.Close()
+
+	for rowsData.Next() {
+		var account dueAccount
+		if errScan := rowsData.Scan(&account.userID, &account.reviewMode); errScan != nil {
+			return nil, structs.Error{
+				Message: errScan.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		dueAccounts = append(dueAccounts, account)
+	}
+	return
+}
+
+// RetrieveAllReviewsByUser fetches every review a user wrote, hidden ones included, oldest first.
+func RetrieveAllReviewsByUser(ctx context.Context, db *sql.DB, userID int) (collection []structs.Review, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryStatement := fmt.Sprintf("SELECT %s FROM %s WHERE r.user_id = $1 ORDER BY r.created_at ASC", reviewColumns, reviewSource)
+
+	rowsData, errQuery := db.QueryContext(ctx, queryStatement, userID)
+	if errQuery != nil {
+		return []structs.Review{}, structs.Error{
+			Message: errQuery.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	defer rowsData.Close()
+
+	collection = []structs.Review{}
+	for rowsData.Next() {
+		var reviewItem = structs.Review{}
+		if errScan := scanReview(rowsData, &reviewItem); errScan != nil {
+			return []structs.Review{}, structs.Error{
+				Message: errScan.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		collection = append(collection, reviewItem)
+	}
+
+	if errAspects := attachAspectRatings(ctx, db, collection); errAspects != nil {
+		return []structs.Review{}, structs.Error{
+			Message: errAspects.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return
+}
+
+// RetrieveUserActivity fetches a user's votes, comments and reports, oldest first.
+func RetrieveUserActivity(ctx context.Context, db *sql.DB, userID int) (collection []structs.ActivityEntry, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryStatement := `SELECT 'vote', review_id, CASE WHEN helpful THEN 'helpful' ELSE 'unhelpful' END, created_at
+                       FROM review_votes WHERE user_id = $1
+                       UNION ALL
+                       SELECT 'comment', review_id, body, created_at FROM review_comments WHERE user_id = $1
+                       UNION ALL
+                       SELECT 'report', review_id, reason, created_at FROM review_reports WHERE user_id = $1
+                       ORDER BY 4 ASC`
+
+	rowsData, errQuery := db.QueryContext(ctx, queryStatement, userID)
+	if errQuery != nil {
+		return []structs.ActivityEntry{}, structs.Error{
+			Message: errQuery.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	defer rowsData.Close()
+
+	collection = []structs.ActivityEntry{}
+	for rowsData.Next() {
+		var activityItem = structs.ActivityEntry{}
+		if errScan := rowsData.Scan(&activityItem.Type, &activityItem.ReviewID, &activityItem.Detail, &activityItem.CreatedAt); errScan != nil {
+			return []structs.ActivityEntry{}, structs.Error{
+				Message: errScan.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		collection = append(collection, activityItem)
+	}
+	return
+}
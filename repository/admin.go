@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sb-go-readrate-nabiel/structs"
+	"strings"
+)
+
+// Actions recorded in the admin audit log.
+const (
+	AdminActionSuspend       = "suspend"
+	AdminActionUnsuspend     = "unsuspend"
+	AdminActionPasswordReset = "force_password_reset"
+	AdminActionChangeRole    = "change_role"
+	AdminActionDelete        = "delete_user"
+	AdminActionUnlock        = "unlock"
+	AdminActionRoleSettings  = "update_role_settings"
+)
+
+// RetrieveUsers fetches one page of users, oldest first. search matches the username, email or
+// display name; role and status ("active" or "suspended") narrow the list when not empty.
+func RetrieveUsers(ctx context.Context, db *sql.DB, search string, role string, status string, page int, pageSize int) (collection []structs.User, total int, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	// Match the search text literally, not as a LIKE pattern
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(search) + "%"
+	conditions := `($1 = '%%' OR username ILIKE $1 OR email ILIKE $1 OR display_name ILIKE $1)
+                   AND ($2 = '' OR role = $2)
+                   AND ($3 = '' OR ($3 = 'suspended') = (suspended_at IS NOT NULL))`
+
+	errCount := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE "+conditions, pattern, role, status).Scan(&total)
+	if errCount != nil {
+		return []structs.User{}, 0, structs.Error{
+			Message: errCount.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	queryStatement := fmt.Sprintf("SELECT %s FROM users WHERE %s ORDER BY id ASC LIMIT $4 OFFSET $5", userColumns, conditions)
+
+	rowsData, errQuery := db.QueryContext(ctx, queryStatement, pattern, role, status, pageSize, (page-1)*pageSize)
+	if errQuery != nil {
+		return []structs.User{}, 0, structs.Error{
+			Message: errQuery.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	defer rowsData.Close()
+
+	collection = []structs.User{}
+	for rowsData.Next() {
+		var userItem = structs.User{}
+		if errScan := scanUser(rowsData, &userItem); errScan != nil {
+			return []structs.User{}, 0, structs.Error{
+				Message: errScan.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		collection = append(collection, userItem)
+	}
+	return
+}
+
+// SuspendUser stops a user from authenticating until they are unsuspended.
+func SuspendUser(ctx context.Context, db *sql.DB, userID int, reason string, adminID int) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryUpdate := `UPDATE users SET suspended_at = NOW(), suspended_by = $2, suspension_reason = $3, modified_by = $2, modified_at = NOW()
+                    WHERE id = $1`
+	return updateUserAsAdmin(ctx, db, userID, adminID, AdminActionSuspend, reason, queryUpdate, userID, adminID, reason)
+}
+
+// UnsuspendUser lets a suspended user authenticate again.
+func UnsuspendUser(ctx context.Context, db *sql.DB, userID int, adminID int) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryUpdate := `UPDATE users SET suspended_at = NULL, suspended_by = NULL, suspension_reason = '', modified_by = $2, modified_at = NOW()
+                    WHERE id = $1`
+	return updateUserAsAdmin(ctx, db, userID, adminID, AdminActionUnsuspend, "", queryUpdate, userID, adminID)
+}
+
+// RequirePasswordReset refuses a user's logins until they reset their password through an emailed
+// token.
+func RequirePasswordReset(ctx context.Context, db *sql.DB, userID int, adminID int) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryUpdate := "UPDATE users SET password_reset_required = TRUE, modified_by = $2, modified_at = NOW() WHERE id = $1"
+	return updateUserAsAdmin(ctx, db, userID, adminID, AdminActionPasswordReset, "", queryUpdate, userID, adminID)
+}
+
+// ChangeUserRole gives a user a new role.
+func ChangeUserRole(ctx context.Context, db *sql.DB, userID int, role string, adminID int) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryUpdate := "UPDATE users SET role = $3, modified_by = $2, modified_at = NOW() WHERE id = $1"
+	return updateUserAsAdmin(ctx, db, userID, adminID, AdminActionChangeRole, role, queryUpdate, userID, adminID, role)
+}
+
+// DeleteUserAsAdmin erases a user's account right away, deleting or anonymizing their reviews.
+func DeleteUserAsAdmin(ctx context.Context, db *sql.DB, userID int, reviewMode string, adminID int) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	return inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		if problem := eraseAccount(ctx, transaction, userID, reviewMode); problem.Message != "" {
+			return problem
+		}
+
+		if errAudit := recordAdminAction(ctx, transaction, adminID, userID, AdminActionDelete, "reviews: "+reviewMode); errAudit != nil {
+			return structs.Error{
+				Message: errAudit.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		return structs.Error{}
+	})
+}
+
+// RecordAdminAction adds an entry to the admin audit log for actions that change nothing else in
+// the database. targetUserID is 0 when the action is not about a user.
+func RecordAdminAction(ctx context.Context, db *sql.DB, adminID int, targetUserID int, action string, detail string) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	return inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		if errAudit := recordAdminAction(ctx, transaction, adminID, targetUserID, action, detail); errAudit != nil {
+			return structs.Error{
+				Message: errAudit.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		return structs.Error{}
+	})
+}
+
+// RetrieveAdminActions fetches one page of the admin audit log, newest first.
+func RetrieveAdminActions(ctx context.Context, db *sql.DB, page int, pageSize int) (collection []structs.AdminAction, total int, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	if errCount := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM admin_actions").Scan(&total); errCount != nil {
+		return []structs.AdminAction{}, 0, structs.Error{
+			Message: errCount.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	queryStatement := `SELECT id, admin_id, COALESCE(target_user_id, 0), action, detail, created_at
+                       FROM admin_actions ORDER BY created_at DESC, id DESC LIMIT $1 OFFSET $2`
+
+	rowsData, errQuery := db.QueryContext(ctx, queryStatement, pageSize, (page-1)*pageSize)
+	if errQuery != nil {
+		return []structs.AdminAction{}, 0, structs.Error{
+			Message: errQuery.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	defer rowsData.Close()
+
+	collection = []structs.AdminAction{}
+	for rowsData.Next() {
+		var actionItem = structs.AdminAction{}
+		errScan := rowsData.Scan(&actionItem.ID, &actionItem.AdminID, &actionItem.TargetUserID, &actionItem.Action, &actionItem.Detail, &actionItem.CreatedAt)
+		if errScan != nil {
+			return []structs.AdminAction{}, 0, structs.Error{
+				Message: errScan.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		collection = append(collection, actionItem)
+	}
+	return
+}
+
+// updateUserAsAdmin runs an update on one user and records it in the audit log, atomically.
+func updateUserAsAdmin(ctx context.Context, db *sql.DB, userID int, adminID int, action string, detail string, queryUpdate string, args ...any) structs.Error {
+	return inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		result, errExec := transaction.ExecContext(ctx, queryUpdate, args...)
+		if errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return structs.Error{
+				Message: fmt.Sprintf("user with identifier %d not found", userID),
+				Status:  http.StatusNotFound,
+			}
+		}
+
+		if errAudit := recordAdminAction(ctx, transaction, adminID, userID, action, detail); errAudit != nil {
+			return structs.Error{
+				Message: errAudit.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		return structs.Error{}
+	})
+}
+
+func recordAdminAction(ctx context.Context, transaction *sql.Tx, adminID int, targetUserID int, action string, detail string) error {
+	queryInsert := "INSERT INTO admin_actions (admin_id, target_user_id, action, detail) VALUES ($1, NULLIF($2, 0), $3, $4)"
+	_, errExec := transaction.ExecContext(ctx, queryInsert, adminID, targetUserID, action, detail)
+	return errExec
+}
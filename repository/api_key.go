@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sb-go-readrate-nabiel/structs"
+
+	"github.com/lib/pq"
+)
+
+const (
+	apiKeyPrefix       = "rr_"
+	apiKeyPrefixLength = 11 // "rr_" and the first 8 characters of the secret part
+)
+
+const apiKeyColumns = "id, user_id, name, prefix, scopes, last_used_at, created_at"
+
+// StoreAPIKey creates a new API key for a user and returns it along with the key itself, which is
+// not stored and cannot be retrieved again.
+func StoreAPIKey(ctx context.Context, db *sql.DB, keyData structs.APIKey) (record structs.APIKey, key string, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	randomBytes := make([]byte, 24)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return record, "", structs.Error{
+			Message: "failed to generate API key: " + err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	key = apiKeyPrefix + hex.EncodeToString(randomBytes)
+
+	queryCommand := fmt.Sprintf(`INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes) VALUES ($1, $2, $3, $4, $5)
+                                 RETURNING %s`, apiKeyColumns)
+
+	errRow := db.QueryRowContext(ctx, queryCommand, keyData.UserID, keyData.Name, key[:apiKeyPrefixLength], hashToken(key), pq.Array(keyData.Scopes)).Scan(
+		&record.ID, &record.UserID, &record.Name, &record.Prefix, pq.Array(&record.Scopes), &record.LastUsedAt, &record.CreatedAt,
+	)
+	if errRow != nil {
+		return record, "", structs.Error{
+			Message: errRow.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return record, key, structs.Error{}
+}
+
+// RetrieveAPIKeys fetches a user's API keys that have not been revoked, newest first.
+func RetrieveAPIKeys(ctx context.Context, db *sql.DB, userID int) (collection []structs.APIKey, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryStatement := fmt.Sprintf("SELECT %s FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC", apiKeyColumns)
+
+	rowsData, errQuery := db.QueryContext(ctx, queryStatement, userID)
+	if errQuery != nil {
+		return []structs.APIKey{}, structs.Error{
+			Message: errQuery.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	defer rowsData.Close()
+
+	collection = []structs.APIKey{}
+	for rowsData.Next() {
+		var keyItem = structs.APIKey{}
+		errScan := rowsData.Scan(
+			&keyItem.ID, &keyItem.UserID, &keyItem.Name, &keyItem.Prefix, pq.Array(&keyItem.Scopes), &keyItem.LastUsedAt, &keyItem.CreatedAt,
+		)
+		if errScan != nil {
+			return []structs.APIKey{}, structs.Error{
+				Message: errScan.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		collection = append(collection, keyItem)
+	}
+	return
+}
+
+// RevokeAPIKey stops one of a user's API keys from working.
+func RevokeAPIKey(ctx context.Context, db *sql.DB, userID int, keyID int) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	result, errExec := db.ExecContext(ctx, "UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL", keyID, userID)
+	if errExec != nil {
+		return structs.Error{
+			Message: errExec.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return structs.Error{
+			Message: fmt.Sprintf("API key with identifier %d not found", keyID),
+			Status:  http.StatusNotFound,
+		}
+	}
+	return
+}
+
+// AuthenticateAPIKey looks up the owner and scopes of an active API key, and whether the owner's
+// role requires two-factor authentication, and records that the key was used. A 401 is returned
+// for unknown or revoked keys.
+func AuthenticateAPIKey(ctx context.Context, db *sql.DB, key string) (account structs.User, scopes []string, roleRequiresTwoFactor bool, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	var keyID int
+	queryLookup := `SELECT k.id, k.scopes, u.id, u.username, u.role, u.totp_enabled, COALESCE(rs.require_two_factor, FALSE),
+                           u.suspended_at IS NOT NULL, u.suspension_reason, u.password_reset_required
+                    FROM api_keys k JOIN users u ON u.id = k.user_id LEFT JOIN role_settings rs ON rs.role = u.role
+                    WHERE k.key_hash = $1 AND k.revoked_at IS NULL`
+
+	errRow := db.QueryRowContext(ctx, queryLookup, hashToken(key)).Scan(&keyID, pq.Array(&scopes), &account.ID, &account.Username, &account.Role,
+		&account.TwoFactorEnabled, &roleRequiresTwoFactor, &account.Suspended, &account.SuspensionReason, &account.PasswordResetRequired)
+	if errRow != nil {
+		if errRow == sql.ErrNoRows {
+			return account, nil, false, structs.Error{
+				Message: "invalid API key",
+				Status:  http.StatusUnauthorized,
+			}
+		}
+		return account, nil, false, structs.Error{
+			Message: errRow.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	// Record the use at most once a minute to spare a write on every request
+	queryTouch := `UPDATE api_keys SET last_used_at = NOW()
+                   WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`
+	if _, errExec := db.ExecContext(ctx, queryTouch, keyID); errExec != nil {
+		return account, nil, false, structs.Error{
+			Message: errExec.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return account, scopes, roleRequiresTwoFactor, structs.Error{}
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net/http"
@@ -9,10 +10,13 @@ import (
 
 const bookColumns = "id, title, category_id, description, image_url, release_year, price, total_page, thickness, created_at, created_by, modified_at, modified_by"
 
-func RetrieveBooks(db *sql.DB) (collection []structs.Book, problem structs.Error) {
+func RetrieveBooks(ctx context.Context, db *sql.DB) (collection []structs.Book, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
 	queryStatement := fmt.Sprintf("SELECT %s FROM books", bookColumns)
 
-	rowsData, errQuery := db.Query(queryStatement)
+	rowsData, errQuery := db.QueryContext(ctx, queryStatement)
 	if errQuery != nil {
 		return []structs.Book{}, structs.Error{
 			Message: errQuery.Error(),
@@ -50,11 +54,14 @@ func RetrieveBooks(db *sql.DB) (collection []structs.Book, problem structs.Error
 	return
 }
 
-func StoreBook(db *sql.DB, bookData structs.Book) (problem structs.Error) {
+func StoreBook(ctx context.Context, db *sql.DB, bookData structs.Book) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
 	queryCommand := `INSERT INTO books (title, category_id, description, image_url, release_year, price, total_page, thickness, created_by, modified_by)
                      VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
 
-	_, errExec := db.Exec(queryCommand, bookData.Title, bookData.CategoryID, bookData.Description, bookData.ImageURL, bookData.ReleaseYear, bookData.Price, bookData.TotalPage, bookData.Thickness, bookData.CreatedBy, bookData.ModifiedBy)
+	_, errExec := db.ExecContext(ctx, queryCommand, bookData.Title, bookData.CategoryID, bookData.Description, bookData.ImageURL, bookData.ReleaseYear, bookData.Price, bookData.TotalPage, bookData.Thickness, bookData.CreatedBy, bookData.ModifiedBy)
 
 	if errExec != nil {
 		return structs.Error{
@@ -65,10 +72,13 @@ func StoreBook(db *sql.DB, bookData structs.Book) (problem structs.Error) {
 	return
 }
 
-func FindSingleBook(db *sql.DB, bookID int) (record structs.Book, problem structs.Error) {
+func FindSingleBook(ctx context.Context, db *sql.DB, bookID int) (record structs.Book, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
 	querySingle := fmt.Sprintf("SELECT %s FROM books WHERE id = $1", bookColumns)
 
-	rowResult := db.QueryRow(querySingle, bookID)
+	rowResult := db.QueryRowContext(ctx, querySingle, bookID)
 
 	errRow := rowResult.Scan(
 		&record.ID,
@@ -101,17 +111,16 @@ func FindSingleBook(db *sql.DB, bookID int) (record structs.Book, problem struct
 	return
 }
 
-func UpdateExistingBook(db *sql.DB, bookData structs.Book) (problem structs.Error) {
-	_, errExistence := FindSingleBook(db, bookData.ID)
-	if errExistence.Message != "" {
-		return errExistence
-	}
+// UpdateExistingBook replaces the fields of a book. A 404 is returned when no book has the ID.
+func UpdateExistingBook(ctx context.Context, db *sql.DB, bookData structs.Book) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
 
 	queryUpdate := `UPDATE books SET title = $1, category_id = $2, description = $3, image_url = $4,
                     release_year = $5, price = $6, total_page = $7, thickness = $8, modified_by = $9,
                     modified_at = NOW() WHERE id = $10`
 
-	_, errExec := db.Exec(queryUpdate, bookData.Title, bookData.CategoryID, bookData.Description, bookData.ImageURL,
+	result, errExec := db.ExecContext(ctx, queryUpdate, bookData.Title, bookData.CategoryID, bookData.Description, bookData.ImageURL,
 		bookData.ReleaseYear, bookData.Price, bookData.TotalPage, bookData.Thickness, bookData.ModifiedBy, bookData.ID)
 
 	if errExec != nil {
@@ -120,17 +129,23 @@ func UpdateExistingBook(db *sql.DB, bookData structs.Book) (problem structs.Erro
 			Status:  http.StatusInternalServerError,
 		}
 	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return structs.Error{
+			Message: fmt.Sprintf("book with identifier %d not found", bookData.ID),
+			Status:  http.StatusNotFound,
+		}
+	}
 	return
 }
 
-func EraseBook(db *sql.DB, bookID int) (problem structs.Error) {
-	_, errExistence := FindSingleBook(db, bookID)
-	if errExistence.Message != "" {
-		return errExistence
-	}
+// EraseBook deletes a book. A 404 is returned when no book has the ID.
+func EraseBook(ctx context.Context, db *sql.DB, bookID int) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
 
 	queryDelete := "DELETE FROM books WHERE id = $1"
-	_, errExec := db.Exec(queryDelete, bookID)
+	result, errExec := db.ExecContext(ctx, queryDelete, bookID)
 
 	if errExec != nil {
 		return structs.Error{
@@ -138,13 +153,23 @@ func EraseBook(db *sql.DB, bookID int) (problem structs.Error) {
 			Status:  http.StatusInternalServerError,
 		}
 	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return structs.Error{
+			Message: fmt.Sprintf("book with identifier %d not found", bookID),
+			Status:  http.StatusNotFound,
+		}
+	}
 	return
 }
 
-func RetrieveBooksForCategory(db *sql.DB, categoryIdentifier int) (collection []structs.Book, problem structs.Error) {
+func RetrieveBooksForCategory(ctx context.Context, db *sql.DB, categoryIdentifier int) (collection []structs.Book, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
 	queryCategory := fmt.Sprintf("SELECT %s FROM books WHERE category_id = $1", bookColumns)
 
-	rowsData, errQuery := db.Query(queryCategory, categoryIdentifier)
+	rowsData, errQuery := db.QueryContext(ctx, queryCategory, categoryIdentifier)
 	if errQuery != nil {
 		return collection, structs.Error{
 			Message: fmt.Sprintf("failed to retrieve books: %s", errQuery.Error()),
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net/http"
@@ -9,10 +10,13 @@ import (
 
 const categoryColumns = "id, name, created_at, created_by, modified_at, modified_by"
 
-func RetrieveCategory(db *sql.DB, categoryID int) (category structs.Category, problem structs.Error) {
+func RetrieveCategory(ctx context.Context, db *sql.DB, categoryID int) (category structs.Category, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
 	queryStatement := fmt.Sprintf("SELECT %s FROM categories WHERE id = $1", categoryColumns)
 
-	errQuery := db.QueryRow(queryStatement, categoryID).Scan(
+	errQuery := db.QueryRowContext(ctx, queryStatement, categoryID).Scan(
 		&category.ID, &category.Name, &category.CreatedAt, &category.CreatedBy, &category.ModifiedAt, &category.ModifiedBy)
 
 	if errQuery != nil {
@@ -30,10 +34,13 @@ func RetrieveCategory(db *sql.DB, categoryID int) (category structs.Category, pr
 	return
 }
 
-func RetrieveCategories(db *sql.DB) (categories []structs.Category, problem structs.Error) {
+func RetrieveCategories(ctx context.Context, db *sql.DB) (categories []structs.Category, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
 	queryStatement := fmt.Sprintf("SELECT %s FROM categories", categoryColumns)
 
-	rowsResult, errQuery := db.Query(queryStatement)
+	rowsResult, errQuery := db.QueryContext(ctx, queryStatement)
 	if errQuery != nil {
 		return []structs.Category{}, structs.Error{
 			Message: errQuery.Error(),
@@ -58,10 +65,13 @@ func RetrieveCategories(db *sql.DB) (categories []structs.Category, problem stru
 	return
 }
 
-func StoreCategory(db *sql.DB, categoryData structs.Category) (problem structs.Error) {
+func StoreCategory(ctx context.Context, db *sql.DB, categoryData structs.Category) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
 	queryCommand := `INSERT INTO categories (name, created_by, modified_by) VALUES ($1, $2, $3)`
 
-	_, errExec := db.Exec(queryCommand, categoryData.Name, categoryData.CreatedBy, categoryData.ModifiedBy)
+	_, errExec := db.ExecContext(ctx, queryCommand, categoryData.Name, categoryData.CreatedBy, categoryData.ModifiedBy)
 
 	if errExec != nil {
 		return structs.Error{
@@ -72,15 +82,13 @@ func StoreCategory(db *sql.DB, categoryData structs.Category) (problem structs.E
 	return
 }
 
-func UpdateCategory(db *sql.DB, categoryData structs.Category) (problem structs.Error) {
-	_, errExistence := RetrieveCategory(db, categoryData.ID)
-	if errExistence.Message != "" {
-		return errExistence
-	}
+func UpdateCategory(ctx context.Context, db *sql.DB, categoryData structs.Category) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
 
 	queryUpdate := `UPDATE categories SET name = $1, modified_by = $2, modified_at = NOW() WHERE id = $3`
 
-	_, errExec := db.Exec(queryUpdate, categoryData.Name, categoryData.ModifiedBy, categoryData.ID)
+	result, errExec := db.ExecContext(ctx, queryUpdate, categoryData.Name, categoryData.ModifiedBy, categoryData.ID)
 
 	if errExec != nil {
 		return structs.Error{
@@ -88,17 +96,22 @@ func UpdateCategory(db *sql.DB, categoryData structs.Category) (problem structs.
 			Status:  http.StatusInternalServerError,
 		}
 	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return structs.Error{
+			Message: fmt.Sprintf("category with identifier %d not found", categoryData.ID),
+			Status:  http.StatusNotFound,
+		}
+	}
 	return
 }
 
-func EraseCategory(db *sql.DB, categoryID int) (problem structs.Error) {
-	_, errExistence := RetrieveCategory(db, categoryID)
-	if errExistence.Message != "" {
-		return errExistence
-	}
+func EraseCategory(ctx context.Context, db *sql.DB, categoryID int) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
 
 	queryDelete := "DELETE FROM categories WHERE id = $1"
-	_, errExec := db.Exec(queryDelete, categoryID)
+	result, errExec := db.ExecContext(ctx, queryDelete, categoryID)
 
 	if errExec != nil {
 		return structs.Error{
@@ -106,5 +119,12 @@ func EraseCategory(db *sql.DB, categoryID int) (problem structs.Error) {
 			Status:  http.StatusInternalServerError,
 		}
 	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return structs.Error{
+			Message: fmt.Sprintf("category with identifier %d not found", categoryID),
+			Status:  http.StatusNotFound,
+		}
+	}
 	return
 }
\ No newline at end of file
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sb-go-readrate-nabiel/structs"
+	"time"
+)
+
+// RetrieveBookRatingStats aggregates review count, average rating and the number of reviews
+// created since windowStart for every book that has at least one visible review.
+func RetrieveBookRatingStats(ctx context.Context, db *sql.DB, windowStart time.Time) (collection []structs.BookRatingStat, problem structs.Error) {
+	ctx, done := withJobTimeout(ctx, &problem)
+	defer done()
+
+	queryStatement := `SELECT b.id, b.category_id, COUNT(r.id), AVG(r.rating),
+                       COUNT(r.id) FILTER (WHERE r.created_at >= $1)
+                       FROM books b JOIN reviews r ON r.book_id = b.id AND NOT r.hidden
+                       GROUP BY b.id, b.category_id`
+
+	rowsData, errQuery := db.QueryContext(ctx, queryStatement, windowStart)
+	if errQuery != nil {
+		return []structs.BookRatingStat{}, structs.Error{
+			Message: fmt.Sprintf("failed to retrieve rating stats: %s", errQuery.Error()),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	defer rowsData.Close()
+
+	for rowsData.Next() {
+		var statItem = structs.BookRatingStat{}
+		errScan := rowsData.Scan(&statItem.BookID, &statItem.CategoryID, &statItem.ReviewCount, &statItem.AverageRating, &statItem.RecentCount)
+
+		if errScan != nil {
+			return []structs.BookRatingStat{}, structs.Error{
+				Message: errScan.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		collection = append(collection, statItem)
+	}
+	return
+}
+
+// ReplaceChartEntries swaps the stored charts for the given entries in a single transaction,
+// so readers never see a half-refreshed chart.
+func ReplaceChartEntries(ctx context.Context, db *sql.DB, entries []structs.ChartEntry) (problem structs.Error) {
+	ctx, done := withJobTimeout(ctx, &problem)
+	defer done()
+
+	return inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		if _, errExec := transaction.ExecContext(ctx, "DELETE FROM chart_entries"); errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		queryCommand := `INSERT INTO chart_entries (chart, category_id, book_id, rank, score, review_count, average_rating, computed_at)
+                         VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+		for _, entry := range entries {
+			_, errExec := transaction.ExecContext(ctx, queryCommand, entry.Chart, entry.CategoryID, entry.BookID, entry.Rank, entry.Score,
+				entry.ReviewCount, entry.AverageRating, entry.ComputedAt)
+
+			if errExec != nil {
+				return structs.Error{
+					Message: errExec.Error(),
+					Status:  http.StatusInternalServerError,
+				}
+			}
+		}
+		return structs.Error{}
+	})
+}
+
+// RetrieveChart fetches the top entries of a materialized chart. A categoryID of 0 selects the
+// chart across all categories.
+func RetrieveChart(ctx context.Context, db *sql.DB, chart string, categoryID int, limit int) (collection []structs.ChartEntry, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryStatement := `SELECT c.chart, c.category_id, c.rank, c.book_id, b.title, c.score, c.review_count, c.average_rating, c.computed_at
+                       FROM chart_entries c JOIN books b ON b.id = c.book_id
+                       WHERE c.chart = $1 AND c.category_id = $2
+                       ORDER BY c.rank LIMIT $3`
+
+	rowsData, errQuery := db.QueryContext(ctx, queryStatement, chart, categoryID, limit)
+	if errQuery != nil {
+		return []structs.ChartEntry{}, structs.Error{
+			Message: fmt.Sprintf("failed to retrieve chart: %s", errQuery.Error()),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	defer rowsData.Close()
+
+	collection = []structs.ChartEntry{}
+	for rowsData.Next() {
+		var entryItem = structs.ChartEntry{}
+		errScan := rowsData.Scan(
+			&entryItem.Chart, &entryItem.CategoryID, &entryItem.Rank, &entryItem.BookID, &entryItem.Title,
+			&entryItem.Score, &entryItem.ReviewCount, &entryItem.AverageRating, &entryItem.ComputedAt,
+		)
+
+		if errScan != nil {
+			return []structs.ChartEntry{}, structs.Error{
+				Message: errScan.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		collection = append(collection, entryItem)
+	}
+	return
+}
@@ -0,0 +1,273 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sb-go-readrate-nabiel/structs"
+
+	"github.com/lib/pq"
+)
+
+const ratingAspectColumns = "a.id, a.code, a.name, a.is_default, a.created_at, a.created_by, a.modified_at, a.modified_by"
+
+func scanRatingAspects(rowsData *sql.Rows) (collection []structs.RatingAspect, problem structs.Error) {
+	collection = []structs.RatingAspect{}
+	for rowsData.Next() {
+		var aspectItem = structs.RatingAspect{}
+		errScan := rowsData.Scan(
+			&aspectItem.ID, &aspectItem.Code, &aspectItem.Name, &aspectItem.IsDefault,
+			&aspectItem.CreatedAt, &aspectItem.CreatedBy, &aspectItem.ModifiedAt, &aspectItem.ModifiedBy,
+		)
+
+		if errScan != nil {
+			return []structs.RatingAspect{}, structs.Error{
+				Message: errScan.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		collection = append(collection, aspectItem)
+	}
+	return
+}
+
+// RetrieveRatingAspects fetches every rating aspect.
+func RetrieveRatingAspects(ctx context.Context, db *sql.DB) (collection []structs.RatingAspect, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryStatement := fmt.Sprintf("SELECT %s FROM rating_aspects a ORDER BY a.id", ratingAspectColumns)
+
+	rowsData, errQuery := db.QueryContext(ctx, queryStatement)
+	if errQuery != nil {
+		return []structs.RatingAspect{}, structs.Error{
+			Message: errQuery.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	defer rowsData.Close()
+
+	return scanRatingAspects(rowsData)
+}
+
+// StoreRatingAspect saves a new rating aspect.
+func StoreRatingAspect(ctx context.Context, db *sql.DB, aspectData structs.RatingAspect) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryCommand := `INSERT INTO rating_aspects (code, name, is_default, created_by, modified_by) VALUES ($1, $2, $3, $4, $5)`
+
+	_, errExec := db.ExecContext(ctx, queryCommand, aspectData.Code, aspectData.Name, aspectData.IsDefault, aspectData.CreatedBy, aspectData.ModifiedBy)
+
+	if errExec != nil {
+		if errExec.Error() == `pq: duplicate key value violates unique constraint "rating_aspects_code_key"` {
+			return structs.Error{
+				Message: fmt.Sprintf("rating aspect %q already exists", aspectData.Code),
+				Status:  http.StatusConflict, // 409 Conflict
+			}
+		}
+		return structs.Error{
+			Message: errExec.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return
+}
+
+// RetrieveAspectsForCategory fetches the aspects reviews in a category can rate: the category's
+// own aspect set, or the default aspects when it has none.
+func RetrieveAspectsForCategory(ctx context.Context, db *sql.DB, categoryID int) (collection []structs.RatingAspect, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryStatement := fmt.Sprintf(`SELECT %s FROM rating_aspects a
+                                   WHERE a.id IN (SELECT aspect_id FROM category_rating_aspects WHERE category_id = $1)
+                                      OR (a.is_default AND NOT EXISTS (SELECT 1 FROM category_rating_aspects WHERE category_id = $1))
+                                   ORDER BY a.id`, ratingAspectColumns)
+
+	rowsData, errQuery := db.QueryContext(ctx, queryStatement, categoryID)
+	if errQuery != nil {
+		return []structs.RatingAspect{}, structs.Error{
+			Message: errQuery.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	defer rowsData.Close()
+
+	return scanRatingAspects(rowsData)
+}
+
+// RetrieveAspectsForBook fetches the aspects reviews of a book can rate, based on its category.
+func RetrieveAspectsForBook(ctx context.Context, db *sql.DB, bookID int) (collection []structs.RatingAspect, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	bookRecord, errExistence := FindSingleBook(ctx, db, bookID)
+	if errExistence.Message != "" {
+		return []structs.RatingAspect{}, errExistence
+	}
+	return RetrieveAspectsForCategory(ctx, db, bookRecord.CategoryID)
+}
+
+// ReplaceCategoryAspects sets the aspect set of a category. An empty list makes the category use
+// the default aspects again.
+func ReplaceCategoryAspects(ctx context.Context, db *sql.DB, categoryID int, aspectCodes []string) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	_, errExistence := RetrieveCategory(ctx, db, categoryID)
+	if errExistence.Message != "" {
+		return errExistence
+	}
+
+	var knownCount int
+	errCount := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM rating_aspects WHERE code = ANY($1)", pq.Array(aspectCodes)).Scan(&knownCount)
+	if errCount != nil {
+		return structs.Error{
+			Message: errCount.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if knownCount != len(uniqueStrings(aspectCodes)) {
+		return structs.Error{
+			Message: "one or more rating aspects do not exist",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	return inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		if _, errExec := transaction.ExecContext(ctx, "DELETE FROM category_rating_aspects WHERE category_id = $1", categoryID); errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		queryInsert := `INSERT INTO category_rating_aspects (category_id, aspect_id)
+                        SELECT $1, id FROM rating_aspects WHERE code = ANY($2)`
+
+		if _, errExec := transaction.ExecContext(ctx, queryInsert, categoryID, pq.Array(aspectCodes)); errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		return structs.Error{}
+	})
+}
+
+// RetrieveBookRatingSummary aggregates the overall rating and each aspect rating of a book's
+// visible reviews.
+func RetrieveBookRatingSummary(ctx context.Context, db *sql.DB, bookID int) (summary structs.BookRatingSummary, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	_, errExistence := FindSingleBook(ctx, db, bookID)
+	if errExistence.Message != "" {
+		return summary, errExistence
+	}
+
+	summary.BookID = bookID
+	summary.Aspects = []structs.AspectRatingSummary{}
+
+	queryOverall := "SELECT COALESCE(AVG(rating), 0), COUNT(*) FROM reviews WHERE book_id = $1 AND NOT hidden"
+	if errQuery := db.QueryRowContext(ctx, queryOverall, bookID).Scan(&summary.AverageRating, &summary.ReviewCount); errQuery != nil {
+		return summary, structs.Error{
+			Message: errQuery.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	queryAspects := `SELECT a.code, a.name, AVG(ar.rating), COUNT(*)
+                     FROM review_aspect_ratings ar
+                     JOIN reviews r ON r.id = ar.review_id AND NOT r.hidden
+                     JOIN rating_aspects a ON a.id = ar.aspect_id
+                     WHERE r.book_id = $1
+                     GROUP BY a.id, a.code, a.name ORDER BY a.id`
+
+	rowsData, errQuery := db.QueryContext(ctx, queryAspects, bookID)
+	if errQuery != nil {
+		return summary, structs.Error{
+			Message: errQuery.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	defer rowsData.Close()
+
+	for rowsData.Next() {
+		var aspectItem = structs.AspectRatingSummary{}
+		if errScan := rowsData.Scan(&aspectItem.Code, &aspectItem.Name, &aspectItem.AverageRating, &aspectItem.RatingCount); errScan != nil {
+			return summary, structs.Error{
+				Message: errScan.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		summary.Aspects = append(summary.Aspects, aspectItem)
+	}
+	return
+}
+
+// replaceAspectRatings overwrites the sub-ratings of a review inside an open transaction.
+func replaceAspectRatings(ctx context.Context, transaction *sql.Tx, reviewID int, aspectRatings map[string]float64) error {
+	if _, errExec := transaction.ExecContext(ctx, "DELETE FROM review_aspect_ratings WHERE review_id = $1", reviewID); errExec != nil {
+		return errExec
+	}
+
+	queryInsert := `INSERT INTO review_aspect_ratings (review_id, aspect_id, rating)
+                    SELECT $1, id, $3 FROM rating_aspects WHERE code = $2`
+
+	for aspectCode, rating := range aspectRatings {
+		if _, errExec := transaction.ExecContext(ctx, queryInsert, reviewID, aspectCode, rating); errExec != nil {
+			return errExec
+		}
+	}
+	return nil
+}
+
+// attachAspectRatings loads the sub-ratings of every review in the slice.
+func attachAspectRatings(ctx context.Context, db *sql.DB, reviews []structs.Review) error {
+	if len(reviews) == 0 {
+		return nil
+	}
+
+	reviewIDs := make([]int64, 0, len(reviews))
+	positions := map[int]int{}
+	for i, review := range reviews {
+		reviewIDs = append(reviewIDs, int64(review.ID))
+		positions[review.ID] = i
+	}
+
+	queryStatement := `SELECT ar.review_id, a.code, ar.rating FROM review_aspect_ratings ar
+                       JOIN rating_aspects a ON a.id = ar.aspect_id
+                       WHERE ar.review_id = ANY($1)`
+
+	rowsData, errQuery := db.QueryContext(ctx, queryStatement, pq.Array(reviewIDs))
+	if errQuery != nil {
+		return errQuery
+	}
+	defer rowsData.Close()
+
+	for rowsData.Next() {
+		var reviewID int
+		var aspectCode string
+		var rating float64
+		if errScan := rowsData.Scan(&reviewID, &aspectCode, &rating); errScan != nil {
+			return errScan
+		}
+		review := &reviews[positions[reviewID]]
+		if review.AspectRatings == nil {
+			review.AspectRatings = map[string]float64{}
+		}
+		review.AspectRatings[aspectCode] = rating
+	}
+	return rowsData.Err()
+}
+
+func uniqueStrings(values []string) map[string]bool {
+	unique := map[string]bool{}
+	for _, value := range values {
+		unique[value] = true
+	}
+	return unique
+}
@@ -1,42 +1,145 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net/http"
 	"sb-go-readrate-nabiel/structs"
 )
 
-const reviewColumns = "id, book_id, user_id, rating, comment, created_at, created_by, modified_at, modified_by"
+const reviewColumns = `r.id, r.book_id, COALESCE(r.user_id, 0), r.rating, r.comment, r.created_at, r.created_by, r.modified_at, r.modified_by,
+                       COALESCE(v.helpful_count, 0), COALESCE(v.unhelpful_count, 0), COALESCE(c.comment_count, 0),
+                       r.hidden, r.contains_spoilers, COALESCE(rv.revision_count, 0)`
 
-// StoreReview saves a new review to the database.
-func StoreReview(db *sql.DB, reviewData structs.Review) (problem structs.Error) {
-	queryCommand := `INSERT INTO reviews (book_id, user_id, rating, comment, created_by, modified_by)
-                     VALUES ($1, $2, $3, $4, $5, $6)`
+// reviewSource joins every review with its aggregated helpfulness votes, comment count and revision count. helpful_score is the lower
+// bound of the Wilson score interval (95% confidence) for the share of helpful votes, so a review
+// with 40 of 50 helpful votes ranks above one with a single helpful vote.
+const reviewSource = `reviews r LEFT JOIN (
+                          SELECT review_id,
+                                 COUNT(*) FILTER (WHERE helpful) AS helpful_count,
+                                 COUNT(*) FILTER (WHERE NOT helpful) AS unhelpful_count,
+                                 ((COUNT(*) FILTER (WHERE helpful) + 1.9208) / COUNT(*)
+                                  - 1.96 * SQRT((COUNT(*) FILTER (WHERE helpful)) * (COUNT(*) FILTER (WHERE NOT helpful)) / COUNT(*)::float + 0.9604) / COUNT(*))
+                                 / (1 + 3.8416 / COUNT(*)) AS helpful_score
+                          FROM review_votes GROUP BY review_id
+                      ) v ON v.review_id = r.id
+                      LEFT JOIN (
+                          SELECT review_id, COUNT(*) AS comment_count FROM review_comments GROUP BY review_id
+                      ) c ON c.review_id = r.id
+                      LEFT JOIN (
+                          SELECT review_id, COUNT(*) AS revision_count FROM review_revisions GROUP BY review_id
+                      ) rv ON rv.review_id = r.id`
 
-	_, errExec := db.Exec(queryCommand, reviewData.BookID, reviewData.UserID, reviewData.Rating, reviewData.Comment, reviewData.CreatedBy, reviewData.ModifiedBy)
+// reviewSortOrders maps the accepted "sort" values to their ORDER BY clauses.
+var reviewSortOrders = map[string]string{
+	"helpful":     "COALESCE(v.helpful_score, 0) DESC, r.created_at DESC",
+	"newest":      "r.created_at DESC",
+	"oldest":      "r.created_at ASC",
+	"rating_high": "r.rating DESC, r.created_at DESC",
+	"rating_low":  "r.rating ASC, r.created_at DESC",
+}
 
-	if errExec != nil {
-		// Check for unique constraint violation (a user can only review a book once)
-		if errExec.Error() == `pq: duplicate key value violates unique constraint "reviews_book_id_user_id_key"` {
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanReview reads a row selected with reviewColumns into a Review.
+func scanReview(scanner rowScanner, record *structs.Review) error {
+	errScan := scanner.Scan(
+		&record.ID, &record.BookID, &record.UserID, &record.Rating, &record.Comment,
+		&record.CreatedAt, &record.CreatedBy, &record.ModifiedAt, &record.ModifiedBy,
+		&record.HelpfulCount, &record.UnhelpfulCount, &record.CommentCount,
+		&record.Hidden, &record.ContainsSpoilers, &record.RevisionCount,
+	)
+	record.Edited = record.RevisionCount > 0
+	return errScan
+}
+
+// StoreReview saves a new review, along with its aspect ratings, to the database and returns its ID.
+// Each of flags, the content filter's reasons for sending the review to moderation, is recorded as
+// a report in the same transaction.
+func StoreReview(ctx context.Context, db *sql.DB, reviewData structs.Review, flags []string) (reviewID int, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	problem = inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		queryCommand := `INSERT INTO reviews (book_id, user_id, rating, comment, contains_spoilers, created_by, modified_by)
+                         VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+		errExec := transaction.QueryRowContext(ctx, queryCommand, reviewData.BookID, reviewData.UserID, reviewData.Rating, reviewData.Comment, reviewData.ContainsSpoilers,
+			reviewData.CreatedBy, reviewData.ModifiedBy).Scan(&reviewID)
+
+		if errExec != nil {
+			// Check for unique constraint violation (a user can only review a book once)
+			if errExec.Error() == `pq: duplicate key value violates unique constraint "reviews_book_id_user_id_key"` {
+				return structs.Error{
+					Message: fmt.Sprintf("user %d has already reviewed book %d", reviewData.UserID, reviewData.BookID),
+					Status:  http.StatusConflict, // 409 Conflict
+				}
+			}
 			return structs.Error{
-				Message: fmt.Sprintf("user %d has already reviewed book %d", reviewData.UserID, reviewData.BookID),
-				Status:  http.StatusConflict, // 409 Conflict
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
 			}
 		}
-		return structs.Error{
-			Message: errExec.Error(),
+
+		if errAspects := replaceAspectRatings(ctx, transaction, reviewID, reviewData.AspectRatings); errAspects != nil {
+			return structs.Error{
+				Message: errAspects.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		if errFlag := flagReview(ctx, transaction, reviewID, flags); errFlag != nil {
+			return structs.Error{
+				Message: errFlag.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		return structs.Error{}
+	})
+	if problem.Message != "" {
+		return 0, problem
+	}
+	return reviewID, structs.Error{}
+}
+
+// CountDuplicateReviews counts the reviews other than excludeReviewID whose comment matches
+// comment, ignoring case and surrounding whitespace.
+func CountDuplicateReviews(ctx context.Context, db *sql.DB, comment string, excludeReviewID int) (count int, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryCount := "SELECT COUNT(*) FROM reviews WHERE LOWER(TRIM(comment)) = LOWER(TRIM($1)) AND id <> $2"
+
+	if errQuery := db.QueryRowContext(ctx, queryCount, comment, excludeReviewID).Scan(&count); errQuery != nil {
+		return 0, structs.Error{
+			Message: errQuery.Error(),
 			Status:  http.StatusInternalServerError,
 		}
 	}
 	return
 }
 
-// RetrieveReviewsForBook fetches all reviews for a specific book.
-func RetrieveReviewsForBook(db *sql.DB, bookID int) (collection []structs.Review, problem structs.Error) {
-	queryStatement := fmt.Sprintf("SELECT %s FROM reviews WHERE book_id = $1 ORDER BY created_at DESC", reviewColumns)
+// RetrieveReviewsForBook fetches all visible reviews for a specific book in the given sort order
+// (helpful, newest, oldest, rating_high or rating_low).
+func RetrieveReviewsForBook(ctx context.Context, db *sql.DB, bookID int, sortOrder string) (collection []structs.Review, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
 
-	rowsData, errQuery := db.Query(queryStatement, bookID)
+	orderClause, known := reviewSortOrders[sortOrder]
+	if !known {
+		return []structs.Review{}, structs.Error{
+			Message: fmt.Sprintf("unknown sort order %q, expected helpful, newest, oldest, rating_high or rating_low", sortOrder),
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	queryStatement := fmt.Sprintf("SELECT %s FROM %s WHERE r.book_id = $1 AND NOT r.hidden ORDER BY %s", reviewColumns, reviewSource, orderClause)
+
+	rowsData, errQuery := db.QueryContext(ctx, queryStatement, bookID)
 	if errQuery != nil {
 		return []structs.Review{}, structs.Error{
 			Message: errQuery.Error(),
@@ -47,10 +150,7 @@ func RetrieveReviewsForBook(db *sql.DB, bookID int) (collection []structs.Review
 
 	for rowsData.Next() {
 		var reviewItem = structs.Review{}
-		errScan := rowsData.Scan(
-			&reviewItem.ID, &reviewItem.BookID, &reviewItem.UserID, &reviewItem.Rating, &reviewItem.Comment,
-			&reviewItem.CreatedAt, &reviewItem.CreatedBy, &reviewItem.ModifiedAt, &reviewItem.ModifiedBy,
-		)
+		errScan := scanReview(rowsData, &reviewItem)
 
 		if errScan != nil {
 			return []structs.Review{}, structs.Error{
@@ -61,6 +161,13 @@ func RetrieveReviewsForBook(db *sql.DB, bookID int) (collection []structs.Review
 		collection = append(collection, reviewItem)
 	}
 
+	if errAspects := attachAspectRatings(ctx, db, collection); errAspects != nil {
+		return []structs.Review{}, structs.Error{
+			Message: errAspects.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
 	if len(collection) == 0 {
 		return collection, structs.Error{
 			Message: fmt.Sprintf("no reviews found for book ID %d", bookID),
@@ -70,11 +177,14 @@ func RetrieveReviewsForBook(db *sql.DB, bookID int) (collection []structs.Review
 	return
 }
 
-// RetrieveReviewsByUser fetches all reviews written by a specific user.
-func RetrieveReviewsByUser(db *sql.DB, userID int) (collection []structs.Review, problem structs.Error) {
-	queryStatement := fmt.Sprintf("SELECT %s FROM reviews WHERE user_id = $1 ORDER BY created_at DESC", reviewColumns)
+// RetrieveReviewsByUser fetches all visible reviews written by a specific user.
+func RetrieveReviewsByUser(ctx context.Context, db *sql.DB, userID int) (collection []structs.Review, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryStatement := fmt.Sprintf("SELECT %s FROM %s WHERE r.user_id = $1 AND NOT r.hidden ORDER BY r.created_at DESC", reviewColumns, reviewSource)
 
-	rowsData, errQuery := db.Query(queryStatement, userID)
+	rowsData, errQuery := db.QueryContext(ctx, queryStatement, userID)
 	if errQuery != nil {
 		return []structs.Review{}, structs.Error{
 			Message: errQuery.Error(),
@@ -85,10 +195,7 @@ func RetrieveReviewsByUser(db *sql.DB, userID int) (collection []structs.Review,
 
 	for rowsData.Next() {
 		var reviewItem = structs.Review{}
-		errScan := rowsData.Scan(
-			&reviewItem.ID, &reviewItem.BookID, &reviewItem.UserID, &reviewItem.Rating, &reviewItem.Comment,
-			&reviewItem.CreatedAt, &reviewItem.CreatedBy, &reviewItem.ModifiedAt, &reviewItem.ModifiedBy,
-		)
+		errScan := scanReview(rowsData, &reviewItem)
 
 		if errScan != nil {
 			return []structs.Review{}, structs.Error{
@@ -99,6 +206,13 @@ func RetrieveReviewsByUser(db *sql.DB, userID int) (collection []structs.Review,
 		collection = append(collection, reviewItem)
 	}
 
+	if errAspects := attachAspectRatings(ctx, db, collection); errAspects != nil {
+		return []structs.Review{}, structs.Error{
+			Message: errAspects.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
 	if len(collection) == 0 {
 		return collection, structs.Error{
 			Message: fmt.Sprintf("no reviews found by user ID %d", userID),
@@ -108,16 +222,53 @@ func RetrieveReviewsByUser(db *sql.DB, userID int) (collection []structs.Review,
 	return
 }
 
+// RetrieveRecentReviewsByUser fetches a user's latest visible reviews, at most limit of them.
+func RetrieveRecentReviewsByUser(ctx context.Context, db *sql.DB, userID int, limit int) (collection []structs.Review, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryStatement := fmt.Sprintf("SELECT %s FROM %s WHERE r.user_id = $1 AND NOT r.hidden ORDER BY r.created_at DESC LIMIT $2", reviewColumns, reviewSource)
+
+	rowsData, errQuery := db.QueryContext(ctx, queryStatement, userID, limit)
+	if errQuery != nil {
+		return []structs.Review{}, structs.Error{
+			Message: errQuery.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	defer rowsData.Close()
+
+	collection = []structs.Review{}
+	for rowsData.Next() {
+		var reviewItem = structs.Review{}
+		if errScan := scanReview(rowsData, &reviewItem); errScan != nil {
+			return []structs.Review{}, structs.Error{
+				Message: errScan.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		collection = append(collection, reviewItem)
+	}
+
+	if errAspects := attachAspectRatings(ctx, db, collection); errAspects != nil {
+		return []structs.Review{}, structs.Error{
+			Message: errAspects.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return
+}
+
 // FindSingleReview retrieves a single review by its ID.
-func FindSingleReview(db *sql.DB, reviewID int) (record structs.Review, problem structs.Error) {
-	querySingle := fmt.Sprintf("SELECT %s FROM reviews WHERE id = $1", reviewColumns)
+func FindSingleReview(ctx context.Context, db *sql.DB, reviewID int) (record structs.Review, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
 
-	rowResult := db.QueryRow(querySingle, reviewID)
+	querySingle := fmt.Sprintf("SELECT %s FROM %s WHERE r.id = $1", reviewColumns, reviewSource)
 
-	errRow := rowResult.Scan(
-		&record.ID, &record.BookID, &record.UserID, &record.Rating, &record.Comment,
-		&record.CreatedAt, &record.CreatedBy, &record.ModifiedAt, &record.ModifiedBy,
-	)
+	rowResult := db.QueryRowContext(ctx, querySingle, reviewID)
+
+	errRow := scanReview(rowResult, &record)
 
 	if errRow != nil {
 		if errRow == sql.ErrNoRows {
@@ -131,40 +282,145 @@ func FindSingleReview(db *sql.DB, reviewID int) (record structs.Review, problem
 			Status:  http.StatusInternalServerError,
 		}
 	}
-	return
+
+	records := []structs.Review{record}
+	if errAspects := attachAspectRatings(ctx, db, records); errAspects != nil {
+		return record, structs.Error{
+			Message: fmt.Sprintf("failed to retrieve review: %s", errAspects.Error()),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return records[0], problem
 }
 
-// UpdateExistingReview updates an existing review.
-func UpdateExistingReview(db *sql.DB, reviewData structs.Review) (problem structs.Error) {
-	// Optional: Check if the review exists before updating
-	_, errExistence := FindSingleReview(db, reviewData.ID)
-	if errExistence.Message != "" {
-		return errExistence
+// findVisibleReview retrieves a review that is not hidden. Hidden reviews are reported as missing
+// (404), like the public listings do.
+func findVisibleReview(ctx context.Context, db *sql.DB, reviewID int) (record structs.Review, problem structs.Error) {
+	record, problem = FindSingleReview(ctx, db, reviewID)
+	if problem.Message == "" && record.Hidden {
+		return structs.Review{}, structs.Error{
+			Message: fmt.Sprintf("review with identifier %d not found", reviewID),
+			Status:  http.StatusNotFound,
+		}
 	}
+	return
+}
 
-	queryUpdate := `UPDATE reviews SET rating = $1, comment = $2, modified_by = $3, modified_at = NOW() WHERE id = $4`
+// UpdateExistingReview updates a review written by reviewData.UserID. When the rating, comment or
+// spoiler flag changes, the previous version is kept in review_revisions. Aspect ratings are only
+// replaced when reviewData carries them. flags are recorded as content filter reports in the same
+// transaction. A 404 is returned when the review does not exist and a 403 when it belongs to
+// someone else.
+func UpdateExistingReview(ctx context.Context, db *sql.DB, reviewData structs.Review, flags []string) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
 
-	_, errExec := db.Exec(queryUpdate, reviewData.Rating, reviewData.Comment, reviewData.ModifiedBy, reviewData.ID)
+	return inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		// Lock the review first so concurrent edits number their revisions one after the other
+		var locked int
+		queryLock := "SELECT id FROM reviews WHERE id = $1 AND user_id = $2 FOR UPDATE"
+		errLock := transaction.QueryRowContext(ctx, queryLock, reviewData.ID, reviewData.UserID).Scan(&locked)
+		if errLock == sql.ErrNoRows {
+			return reviewNotOwned(ctx, transaction, reviewData.ID)
+		}
+		if errLock != nil {
+			return structs.Error{
+				Message: errLock.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
 
-	if errExec != nil {
-		return structs.Error{
-			Message: errExec.Error(),
+		queryRevision := `INSERT INTO review_revisions (review_id, revision, rating, comment, contains_spoilers, written_at, created_by)
+                          SELECT r.id, (SELECT COUNT(*) + 1 FROM review_revisions WHERE review_id = r.id),
+                                 r.rating, r.comment, r.contains_spoilers, r.modified_at, $5
+                          FROM reviews r
+                          WHERE r.id = $1 AND r.user_id = $6
+                            AND (r.rating, r.comment, r.contains_spoilers) IS DISTINCT FROM ($2::numeric, $3::text, $4::boolean)`
+
+		_, errRevision := transaction.ExecContext(ctx, queryRevision, reviewData.ID, reviewData.Rating, reviewData.Comment, reviewData.ContainsSpoilers,
+			reviewData.ModifiedBy, reviewData.UserID)
+		if errRevision != nil {
+			return structs.Error{
+				Message: errRevision.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		queryUpdate := `UPDATE reviews SET rating = $1, comment = $2, contains_spoilers = $3, modified_by = $4, modified_at = NOW()
+                        WHERE id = $5 AND user_id = $6`
+
+		_, errExec := transaction.ExecContext(ctx, queryUpdate, reviewData.Rating, reviewData.Comment, reviewData.ContainsSpoilers,
+			reviewData.ModifiedBy, reviewData.ID, reviewData.UserID)
+		if errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		if reviewData.AspectRatings != nil {
+			if errAspects := replaceAspectRatings(ctx, transaction, reviewData.ID, reviewData.AspectRatings); errAspects != nil {
+				return structs.Error{
+					Message: errAspects.Error(),
+					Status:  http.StatusInternalServerError,
+				}
+			}
+		}
+
+		if errFlag := flagReview(ctx, transaction, reviewData.ID, flags); errFlag != nil {
+			return structs.Error{
+				Message: errFlag.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		return structs.Error{}
+	})
+}
+
+// RetrieveReviewRevisions fetches every prior version of a review, oldest first.
+func RetrieveReviewRevisions(ctx context.Context, db *sql.DB, reviewID int) (collection []structs.ReviewRevision, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryStatement := `SELECT id, review_id, revision, rating, COALESCE(comment, ''), contains_spoilers, written_at, created_at
+                       FROM review_revisions WHERE review_id = $1 ORDER BY revision ASC`
+
+	rowsData, errQuery := db.QueryContext(ctx, queryStatement, reviewID)
+	if errQuery != nil {
+		return []structs.ReviewRevision{}, structs.Error{
+			Message: errQuery.Error(),
 			Status:  http.StatusInternalServerError,
 		}
 	}
+	defer rowsData.Close()
+
+	collection = []structs.ReviewRevision{}
+	for rowsData.Next() {
+		var revisionItem = structs.ReviewRevision{}
+		errScan := rowsData.Scan(
+			&revisionItem.ID, &revisionItem.ReviewID, &revisionItem.Revision, &revisionItem.Rating, &revisionItem.Comment,
+			&revisionItem.ContainsSpoilers, &revisionItem.WrittenAt, &revisionItem.ReplacedAt,
+		)
+
+		if errScan != nil {
+			return []structs.ReviewRevision{}, structs.Error{
+				Message: errScan.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		collection = append(collection, revisionItem)
+	}
 	return
 }
 
-// EraseReview deletes a review by its ID.
-func EraseReview(db *sql.DB, reviewID int) (problem structs.Error) {
-	// Optional: Check if the review exists before deleting
-	_, errExistence := FindSingleReview(db, reviewID)
-	if errExistence.Message != "" {
-		return errExistence
-	}
+// EraseReview deletes any review by its ID. Its votes and comments are removed by ON DELETE
+// CASCADE. A 404 is returned when the review does not exist.
+func EraseReview(ctx context.Context, db *sql.DB, reviewID int) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
 
 	queryDelete := "DELETE FROM reviews WHERE id = $1"
-	_, errExec := db.Exec(queryDelete, reviewID)
+	result, errExec := db.ExecContext(ctx, queryDelete, reviewID)
 
 	if errExec != nil {
 		return structs.Error{
@@ -172,5 +428,58 @@ func EraseReview(db *sql.DB, reviewID int) (problem structs.Error) {
 			Status:  http.StatusInternalServerError,
 		}
 	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return structs.Error{
+			Message: fmt.Sprintf("review with identifier %d not found", reviewID),
+			Status:  http.StatusNotFound,
+		}
+	}
 	return
-}
\ No newline at end of file
+}
+
+// EraseOwnReview deletes a review written by userID. A 404 is returned when the review does not
+// exist and a 403 when it belongs to someone else.
+func EraseOwnReview(ctx context.Context, db *sql.DB, reviewID int, userID int) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	return inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		queryDelete := "DELETE FROM reviews WHERE id = $1 AND user_id = $2"
+		result, errExec := transaction.ExecContext(ctx, queryDelete, reviewID, userID)
+
+		if errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return reviewNotOwned(ctx, transaction, reviewID)
+		}
+		return structs.Error{}
+	})
+}
+
+// reviewNotOwned explains why a write limited to the author's review affected no row: the review
+// does not exist (404) or belongs to another user (403).
+func reviewNotOwned(ctx context.Context, transaction *sql.Tx, reviewID int) structs.Error {
+	var exists bool
+	queryExists := "SELECT EXISTS (SELECT 1 FROM reviews WHERE id = $1)"
+	if errQuery := transaction.QueryRowContext(ctx, queryExists, reviewID).Scan(&exists); errQuery != nil {
+		return structs.Error{
+			Message: errQuery.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if !exists {
+		return structs.Error{
+			Message: fmt.Sprintf("review with identifier %d not found", reviewID),
+			Status:  http.StatusNotFound,
+		}
+	}
+	return structs.Error{
+		Message: fmt.Sprintf("review with identifier %d belongs to another user", reviewID),
+		Status:  http.StatusForbidden,
+	}
+}
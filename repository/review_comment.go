@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sb-go-readrate-nabiel/structs"
+
+	"github.com/lib/pq"
+)
+
+const reviewCommentColumns = "id, review_id, COALESCE(user_id, 0), parent_id, body, created_at, created_by, modified_at, modified_by"
+
+// scanReviewComment reads a row selected with reviewCommentColumns into a ReviewComment.
+func scanReviewComment(scanner rowScanner, record *structs.ReviewComment) error {
+	var parentID sql.NullInt64
+	errScan := scanner.Scan(
+		&record.ID, &record.ReviewID, &record.UserID, &parentID, &record.Body,
+		&record.CreatedAt, &record.CreatedBy, &record.ModifiedAt, &record.ModifiedBy,
+	)
+	if errScan == nil && parentID.Valid {
+		parent := int(parentID.Int64)
+		record.ParentID = &parent
+	}
+	return errScan
+}
+
+// StoreReviewComment saves a new comment on a review. Replies must target a top-level comment
+// on the same review. Hidden reviews cannot be commented on. The checks are part of the insert, so
+// a review hidden at the same time cannot end up with the comment.
+func StoreReviewComment(ctx context.Context, db *sql.DB, commentData structs.ReviewComment) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryCommand := `INSERT INTO review_comments (review_id, user_id, parent_id, body, created_by, modified_by)
+                     SELECT $1, $2, $3, $4, $5, $6
+                     WHERE EXISTS (SELECT 1 FROM reviews WHERE id = $1 AND NOT hidden)
+                       AND ($3::INT IS NULL OR EXISTS (SELECT 1 FROM review_comments
+                                                       WHERE id = $3 AND review_id = $1 AND parent_id IS NULL))`
+
+	result, errExec := db.ExecContext(ctx, queryCommand, commentData.ReviewID, commentData.UserID, commentData.ParentID, commentData.Body, commentData.CreatedBy, commentData.ModifiedBy)
+
+	if errExec != nil {
+		return structs.Error{
+			Message: errExec.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return commentRefused(ctx, db, commentData)
+	}
+	return
+}
+
+// commentRefused explains why StoreReviewComment inserted no row: the review is missing or hidden
+// (404), the parent comment is missing (404), or it is on another review or is itself a reply (400).
+func commentRefused(ctx context.Context, db *sql.DB, commentData structs.ReviewComment) structs.Error {
+	if _, errExistence := findVisibleReview(ctx, db, commentData.ReviewID); errExistence.Message != "" {
+		return errExistence
+	}
+
+	if commentData.ParentID != nil {
+		parentComment, errParent := FindSingleReviewComment(ctx, db, *commentData.ParentID)
+		if errParent.Message != "" {
+			return errParent
+		}
+		if parentComment.ReviewID != commentData.ReviewID {
+			return structs.Error{
+				Message: fmt.Sprintf("comment %d does not belong to review %d", parentComment.ID, commentData.ReviewID),
+				Status:  http.StatusBadRequest,
+			}
+		}
+		if parentComment.ParentID != nil {
+			return structs.Error{
+				Message: "replies can only be made to top-level comments",
+				Status:  http.StatusBadRequest,
+			}
+		}
+	}
+
+	// The review or the parent changed back between the insert and these checks
+	return structs.Error{
+		Message: fmt.Sprintf("review with identifier %d changed while the comment was being saved, try again", commentData.ReviewID),
+		Status:  http.StatusConflict,
+	}
+}
+
+// RetrieveReviewComments fetches one page of top-level comments for a review, oldest first, with
+// their replies attached. total is the number of top-level comments across all pages. The comments
+// of a hidden review are not public.
+func RetrieveReviewComments(ctx context.Context, db *sql.DB, reviewID int, page int, pageSize int) (collection []structs.ReviewComment, total int, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	if _, errExistence := findVisibleReview(ctx, db, reviewID); errExistence.Message != "" {
+		return []structs.ReviewComment{}, 0, errExistence
+	}
+
+	errCount := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM review_comments WHERE review_id = $1 AND parent_id IS NULL", reviewID).Scan(&total)
+	if errCount != nil {
+		return []structs.ReviewComment{}, 0, structs.Error{
+			Message: errCount.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	queryStatement := fmt.Sprintf(`SELECT %s FROM review_comments WHERE review_id = $1 AND parent_id IS NULL
+                                   ORDER BY created_at ASC, id ASC LIMIT $2 OFFSET $3`, reviewCommentColumns)
+
+	rowsData, errQuery := db.QueryContext(ctx, queryStatement, reviewID, pageSize, (page-1)*pageSize)
+	if errQuery != nil {
+		return []structs.ReviewComment{}, 0, structs.Error{
+			Message: errQuery.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	defer rowsData.Close()
+
+	parentIDs := []int64{}
+	positions := map[int]int{}
+	for rowsData.Next() {
+		var commentItem = structs.ReviewComment{}
+		if errScan := scanReviewComment(rowsData, &commentItem); errScan != nil {
+			return []structs.ReviewComment{}, 0, structs.Error{
+				Message: errScan.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		positions[commentItem.ID] = len(collection)
+		parentIDs = append(parentIDs, int64(commentItem.ID))
+		collection = append(collection, commentItem)
+	}
+
+	if len(parentIDs) == 0 {
+		return
+	}
+
+	queryReplies := fmt.Sprintf("SELECT %s FROM review_comments WHERE parent_id = ANY($1) ORDER BY created_at ASC, id ASC", reviewCommentColumns)
+
+	replyRows, errReplies := db.QueryContext(ctx, queryReplies, pq.Array(parentIDs))
+	if errReplies != nil {
+		return []structs.ReviewComment{}, 0, structs.Error{
+			Message: errReplies.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	defer replyRows.Close()
+
+	for replyRows.Next() {
+		var replyItem = structs.ReviewComment{}
+		if errScan := scanReviewComment(replyRows, &replyItem); errScan != nil {
+			return []structs.ReviewComment{}, 0, structs.Error{
+				Message: errScan.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		parent := &collection[positions[*replyItem.ParentID]]
+		parent.Replies = append(parent.Replies, replyItem)
+	}
+	return
+}
+
+// FindSingleReviewComment retrieves a single comment by its ID.
+func FindSingleReviewComment(ctx context.Context, db *sql.DB, commentID int) (record structs.ReviewComment, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	querySingle := fmt.Sprintf("SELECT %s FROM review_comments WHERE id = $1", reviewCommentColumns)
+
+	errRow := scanReviewComment(db.QueryRowContext(ctx, querySingle, commentID), &record)
+
+	if errRow != nil {
+		if errRow == sql.ErrNoRows {
+			return record, structs.Error{
+				Message: fmt.Sprintf("comment with identifier %d not found", commentID),
+				Status:  http.StatusNotFound,
+			}
+		}
+		return record, structs.Error{
+			Message: fmt.Sprintf("failed to retrieve comment: %s", errRow.Error()),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return
+}
+
+// UpdateExistingReviewComment updates the body of a comment written by commentData.UserID. A 404
+// is returned when the comment does not exist and a 403 when it belongs to someone else.
+func UpdateExistingReviewComment(ctx context.Context, db *sql.DB, commentData structs.ReviewComment) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	return inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		queryUpdate := `UPDATE review_comments SET body = $1, modified_by = $2, modified_at = NOW() WHERE id = $3 AND user_id = $4`
+		result, errExec := transaction.ExecContext(ctx, queryUpdate, commentData.Body, commentData.ModifiedBy, commentData.ID, commentData.UserID)
+
+		if errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return commentNotOwned(ctx, transaction, commentData.ID)
+		}
+		return structs.Error{}
+	})
+}
+
+// EraseOwnReviewComment deletes a comment written by userID, along with its replies. A comment other
+// users have replied to is kept without its author and with "[deleted]" as the text, so their
+// replies stay. A 404 is returned when the comment does not exist and a 403 when it belongs to
+// someone else.
+func EraseOwnReviewComment(ctx context.Context, db *sql.DB, commentID int, userID int) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	return inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		queryDetach := `UPDATE review_comments c SET user_id = NULL, body = '[deleted]', modified_at = NOW(), modified_by = $2
+                        WHERE c.id = $1 AND c.user_id = $2
+                          AND EXISTS (SELECT 1 FROM review_comments r WHERE r.parent_id = c.id AND r.user_id IS DISTINCT FROM $2)`
+		result, errExec := transaction.ExecContext(ctx, queryDetach, commentID, userID)
+		if errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		if affected, _ := result.RowsAffected(); affected > 0 {
+			// The author's own replies go with their text
+			queryOwnReplies := "DELETE FROM review_comments WHERE parent_id = $1 AND user_id = $2"
+			if _, errExec := transaction.ExecContext(ctx, queryOwnReplies, commentID, userID); errExec != nil {
+				return structs.Error{
+					Message: errExec.Error(),
+					Status:  http.StatusInternalServerError,
+				}
+			}
+			return structs.Error{}
+		}
+
+		queryDelete := "DELETE FROM review_comments WHERE id = $1 AND user_id = $2"
+		result, errExec = transaction.ExecContext(ctx, queryDelete, commentID, userID)
+
+		if errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return commentNotOwned(ctx, transaction, commentID)
+		}
+		return structs.Error{}
+	})
+}
+
+// commentNotOwned explains why a write limited to the author's comment affected no row: the
+// comment does not exist (404) or belongs to another user (403).
+func commentNotOwned(ctx context.Context, transaction *sql.Tx, commentID int) structs.Error {
+	var exists bool
+	queryExists := "SELECT EXISTS (SELECT 1 FROM review_comments WHERE id = $1)"
+	if errQuery := transaction.QueryRowContext(ctx, queryExists, commentID).Scan(&exists); errQuery != nil {
+		return structs.Error{
+			Message: errQuery.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if !exists {
+		return structs.Error{
+			Message: fmt.Sprintf("comment with identifier %d not found", commentID),
+			Status:  http.StatusNotFound,
+		}
+	}
+	return structs.Error{
+		Message: fmt.Sprintf("comment with identifier %d belongs to another user", commentID),
+		Status:  http.StatusForbidden,
+	}
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sb-go-readrate-nabiel/structs"
+
+	"github.com/lib/pq"
+)
+
+const reviewReportColumns = "id, review_id, COALESCE(user_id, 0), reason, status, created_at, created_by, modified_at, modified_by"
+
+// StoreReviewReport saves a report against a review. Once a review collects hideThreshold open
+// reports from users it is hidden automatically until a moderator approves it; a threshold of 0
+// disables automatic hiding. Flags raised by the content filter do not count.
+func StoreReviewReport(ctx context.Context, db *sql.DB, reportData structs.ReviewReport, hideThreshold int) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	return inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		queryCommand := `INSERT INTO review_reports (review_id, user_id, reason, created_by, modified_by)
+                         VALUES ($1, $2, $3, $4, $5)`
+
+		_, errExec := transaction.ExecContext(ctx, queryCommand, reportData.ReviewID, reportData.UserID, reportData.Reason, reportData.CreatedBy, reportData.ModifiedBy)
+
+		if errExec != nil {
+			// Check for unique constraint violation (a user can only report a review once)
+			if errExec.Error() == `pq: duplicate key value violates unique constraint "review_reports_review_id_user_id_key"` {
+				return structs.Error{
+					Message: fmt.Sprintf("user %d has already reported review %d", reportData.UserID, reportData.ReviewID),
+					Status:  http.StatusConflict, // 409 Conflict
+				}
+			}
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		if hideThreshold > 0 {
+			queryHide := `UPDATE reviews SET hidden = TRUE
+                          WHERE id = $1 AND (SELECT COUNT(*) FROM review_reports
+                                           WHERE review_id = $1 AND user_id IS NOT NULL AND status = 'open') >= $2`
+
+			if _, errHide := transaction.ExecContext(ctx, queryHide, reportData.ReviewID, hideThreshold); errHide != nil {
+				return structs.Error{
+					Message: errHide.Error(),
+					Status:  http.StatusInternalServerError,
+				}
+			}
+		}
+		return structs.Error{}
+	})
+}
+
+// flagReview sends a review to the moderation queue on behalf of the content filter, one report
+// per reason. Flagged reviews stay visible until a moderator hides them.
+func flagReview(ctx context.Context, transaction *sql.Tx, reviewID int, reasons []string) error {
+	queryCommand := `INSERT INTO review_reports (review_id, user_id, reason, created_by, modified_by)
+                     VALUES ($1, NULL, $2, 0, 0)`
+
+	for _, reason := range reasons {
+		if _, errExec := transaction.ExecContext(ctx, queryCommand, reviewID, "content filter: "+reason); errExec != nil {
+			return errExec
+		}
+	}
+	return nil
+}
+
+// RetrieveModerationQueue fetches one page of reviews with open reports, most reported first.
+// total is the number of reported reviews across all pages.
+func RetrieveModerationQueue(ctx context.Context, db *sql.DB, page int, pageSize int) (collection []structs.ModerationQueueItem, total int, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	errCount := db.QueryRowContext(ctx, "SELECT COUNT(DISTINCT review_id) FROM review_reports WHERE status = 'open'").Scan(&total)
+	if errCount != nil {
+		return []structs.ModerationQueueItem{}, 0, structs.Error{
+			Message: errCount.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	queryStatement := fmt.Sprintf(`SELECT %s FROM %s
+                                   JOIN (SELECT review_id, COUNT(*) AS open_reports, MIN(created_at) AS first_reported_at
+                                         FROM review_reports WHERE status = 'open' GROUP BY review_id) q ON q.review_id = r.id
+                                   ORDER BY q.open_reports DESC, q.first_reported_at ASC LIMIT $1 OFFSET $2`, reviewColumns, reviewSource)
+
+	rowsData, errQuery := db.QueryContext(ctx, queryStatement, pageSize, (page-1)*pageSize)
+	if errQuery != nil {
+		return []structs.ModerationQueueItem{}, 0, structs.Error{
+			Message: errQuery.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	defer rowsData.Close()
+
+	reviewIDs := []int64{}
+	positions := map[int]int{}
+	for rowsData.Next() {
+		var queueItem = structs.ModerationQueueItem{Reports: []structs.ReviewReport{}}
+		if errScan := scanReview(rowsData, &queueItem.Review); errScan != nil {
+			return []structs.ModerationQueueItem{}, 0, structs.Error{
+				Message: errScan.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		positions[queueItem.Review.ID] = len(collection)
+		reviewIDs = append(reviewIDs, int64(queueItem.Review.ID))
+		collection = append(collection, queueItem)
+	}
+
+	if len(reviewIDs) == 0 {
+		return
+	}
+
+	queryReports := fmt.Sprintf("SELECT %s FROM review_reports WHERE status = 'open' AND review_id = ANY($1) ORDER BY created_at ASC", reviewReportColumns)
+
+	reportRows, errReports := db.QueryContext(ctx, queryReports, pq.Array(reviewIDs))
+	if errReports != nil {
+		return []structs.ModerationQueueItem{}, 0, structs.Error{
+			Message: errReports.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	defer reportRows.Close()
+
+	for reportRows.Next() {
+		var reportItem = structs.ReviewReport{}
+		errScan := reportRows.Scan(
+			&reportItem.ID, &reportItem.ReviewID, &reportItem.UserID, &reportItem.Reason, &reportItem.Status,
+			&reportItem.CreatedAt, &reportItem.CreatedBy, &reportItem.ModifiedAt, &reportItem.ModifiedBy,
+		)
+		if errScan != nil {
+			return []structs.ModerationQueueItem{}, 0, structs.Error{
+				Message: errScan.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		queueItem := &collection[positions[reportItem.ReviewID]]
+		queueItem.Reports = append(queueItem.Reports, reportItem)
+	}
+	return
+}
+
+// ModerateReview sets whether a review is hidden and resolves all of its open reports.
+func ModerateReview(ctx context.Context, db *sql.DB, reviewID int, hidden bool, moderatorID int) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	return inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		result, errExec := transaction.ExecContext(ctx, "UPDATE reviews SET hidden = $1 WHERE id = $2", hidden, reviewID)
+		if errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return structs.Error{
+				Message: fmt.Sprintf("review with identifier %d not found", reviewID),
+				Status:  http.StatusNotFound,
+			}
+		}
+
+		queryResolve := `UPDATE review_reports SET status = 'resolved', modified_by = $1, modified_at = NOW()
+                         WHERE review_id = $2 AND status = 'open'`
+
+		if _, errExec := transaction.ExecContext(ctx, queryResolve, moderatorID, reviewID); errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		return structs.Error{}
+	})
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sb-go-readrate-nabiel/structs"
+)
+
+// StoreReviewVote records a helpful or unhelpful vote on a review. A 404 is returned when the
+// review does not exist or is hidden.
+func StoreReviewVote(ctx context.Context, db *sql.DB, voteData structs.ReviewVote) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryCommand := `INSERT INTO review_votes (review_id, user_id, helpful, created_by, modified_by)
+                     SELECT id, $2, $3, $4, $5 FROM reviews WHERE id = $1 AND NOT hidden`
+
+	result, errExec := db.ExecContext(ctx, queryCommand, voteData.ReviewID, voteData.UserID, *voteData.Helpful, voteData.CreatedBy, voteData.ModifiedBy)
+
+	if errExec != nil {
+		// Check for unique constraint violation (a user can only vote on a review once)
+		if errExec.Error() == `pq: duplicate key value violates unique constraint "review_votes_review_id_user_id_key"` {
+			return structs.Error{
+				Message: fmt.Sprintf("user %d has already voted on review %d", voteData.UserID, voteData.ReviewID),
+				Status:  http.StatusConflict, // 409 Conflict
+			}
+		}
+		return structs.Error{
+			Message: errExec.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return structs.Error{
+			Message: fmt.Sprintf("review with identifier %d not found", voteData.ReviewID),
+			Status:  http.StatusNotFound,
+		}
+	}
+	return
+}
+
+// EraseReviewVote withdraws a user's vote on a review. Votes on hidden reviews cannot be withdrawn.
+func EraseReviewVote(ctx context.Context, db *sql.DB, reviewID int, userID int) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryDelete := `DELETE FROM review_votes
+                    WHERE review_id = $1 AND user_id = $2 AND review_id IN (SELECT id FROM reviews WHERE NOT hidden)`
+	result, errExec := db.ExecContext(ctx, queryDelete, reviewID, userID)
+
+	if errExec != nil {
+		return structs.Error{
+			Message: errExec.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return structs.Error{
+			Message: fmt.Sprintf("user %d has not voted on review %d", userID, reviewID),
+			Status:  http.StatusNotFound,
+		}
+	}
+	return
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"sb-go-readrate-nabiel/structs"
+	"time"
+)
+
+const sessionTokenPrefix = "rs_"
+
+// StoreSession starts a session for a user that lasts ttl and returns its bearer token, which is
+// not stored. The user's expired sessions are removed at the same time.
+func StoreSession(ctx context.Context, db *sql.DB, userID int, ttl time.Duration) (token string, expiresAt time.Time, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", expiresAt, structs.Error{
+			Message: "failed to generate session token: " + err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	token = sessionTokenPrefix + hex.EncodeToString(randomBytes)
+	expiresAt = time.Now().Add(ttl)
+
+	problem = inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		if _, errExec := transaction.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = $1 AND expires_at <= NOW()", userID); errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		queryInsert := "INSERT INTO sessions (user_id, token_hash, expires_at) VALUES ($1, $2, $3)"
+		if _, errExec := transaction.ExecContext(ctx, queryInsert, userID, hashToken(token), expiresAt); errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		return structs.Error{}
+	})
+	if problem.Message != "" {
+		return "", time.Time{}, problem
+	}
+	return token, expiresAt, structs.Error{}
+}
+
+// AuthenticateSession looks up the owner of an unexpired session token, whether the owner's role
+// requires two-factor authentication, and records that the session was used. A 401 is returned
+// for unknown or expired tokens.
+func AuthenticateSession(ctx context.Context, db *sql.DB, token string) (account structs.User, roleRequiresTwoFactor bool, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	var sessionID int
+	queryLookup := `SELECT s.id, u.id, u.username, u.role, u.totp_enabled, COALESCE(rs.require_two_factor, FALSE),
+                           u.suspended_at IS NOT NULL, u.suspension_reason, u.password_reset_required
+                    FROM sessions s JOIN users u ON u.id = s.user_id LEFT JOIN role_settings rs ON rs.role = u.role
+                    WHERE s.token_hash = $1 AND s.expires_at > NOW()`
+
+	errRow := db.QueryRowContext(ctx, queryLookup, hashToken(token)).Scan(&sessionID, &account.ID, &account.Username, &account.Role,
+		&account.TwoFactorEnabled, &roleRequiresTwoFactor, &account.Suspended, &account.SuspensionReason, &account.PasswordResetRequired)
+	if errRow != nil {
+		if errRow == sql.ErrNoRows {
+			return account, false, structs.Error{
+				Message: "invalid or expired session token",
+				Status:  http.StatusUnauthorized,
+			}
+		}
+		return account, false, structs.Error{
+			Message: errRow.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	// Record the use at most once a minute to spare a write on every request
+	queryTouch := `UPDATE sessions SET last_used_at = NOW()
+                   WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`
+	if _, errExec := db.ExecContext(ctx, queryTouch, sessionID); errExec != nil {
+		return account, false, structs.Error{
+			Message: errExec.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return account, roleRequiresTwoFactor, structs.Error{}
+}
+
+// RevokeSession ends the session of a token.
+func RevokeSession(ctx context.Context, db *sql.DB, token string) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	if _, errExec := db.ExecContext(ctx, "DELETE FROM sessions WHERE token_hash = $1", hashToken(token)); errExec != nil {
+		return structs.Error{
+			Message: errExec.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return
+}
+
+// revokeSessions ends every session of a user inside an open transaction, such as when their
+// password changes.
+func revokeSessions(ctx context.Context, transaction *sql.Tx, userID int) error {
+	_, errExec := transaction.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = $1", userID)
+	return errExec
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sb-go-readrate-nabiel/structs"
+	"time"
+)
+
+// QueryTimeout bounds how long the queries of a single repository call may take. Set from main;
+// zero leaves only the caller's context to end them.
+var QueryTimeout = 5 * time.Second
+
+// JobTimeout replaces QueryTimeout for the calls made by background jobs, such as a chart refresh,
+// which work through whole tables. Set from main; zero leaves only the caller's context to end them.
+var JobTimeout = 5 * time.Minute
+
+// withTimeout limits ctx to QueryTimeout. The returned function releases the context and must be
+// deferred; when the call failed because the context ended, it replaces the database error in
+// problem with a 504 if the database was too slow or a 503 if the request was canceled.
+func withTimeout(ctx context.Context, problem *structs.Error) (context.Context, func()) {
+	return limitContext(ctx, problem, QueryTimeout)
+}
+
+// withJobTimeout is withTimeout for background jobs, limiting ctx to JobTimeout instead.
+func withJobTimeout(ctx context.Context, problem *structs.Error) (context.Context, func()) {
+	return limitContext(ctx, problem, JobTimeout)
+}
+
+func limitContext(ctx context.Context, problem *structs.Error, timeout time.Duration) (context.Context, func()) {
+	cancel := func() {}
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {
+		if problem.Status == http.StatusInternalServerError {
+			if timeoutProblem, ended := contextProblem(ctx); ended {
+				*problem = timeoutProblem
+			}
+		}
+		cancel()
+	}
+}
+
+// contextProblem describes why ctx ended, if it has.
+func contextProblem(ctx context.Context) (problem structs.Error, ended bool) {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return structs.Error{
+			Message: "the database did not answer in time, try again later",
+			Status:  http.StatusGatewayTimeout,
+		}, true
+	case errors.Is(ctx.Err(), context.Canceled):
+		return structs.Error{
+			Message: "the request was canceled before the database answered",
+			Status:  http.StatusServiceUnavailable,
+		}, true
+	}
+	return problem, false
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sb-go-readrate-nabiel/structs"
+)
+
+// inTransaction runs work in a single transaction. The transaction is committed when work returns
+// no problem and rolled back otherwise, so checks made inside work hold for its writes.
+func inTransaction(ctx context.Context, db *sql.DB, work func(transaction *sql.Tx) structs.Error) (problem structs.Error) {
+	transaction, errBegin := db.BeginTx(ctx, nil)
+	if errBegin != nil {
+		return structs.Error{
+			Message: errBegin.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	defer transaction.Rollback()
+
+	if problem = work(transaction); problem.Message != "" {
+		return problem
+	}
+
+	if errCommit := transaction.Commit(); errCommit != nil {
+		return structs.Error{
+			Message: errCommit.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return
+}
@@ -0,0 +1,297 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"fmt"
+	"net/http"
+	"sb-go-readrate-nabiel/structs"
+	"strings"
+)
+
+const recoveryCodeCount = 10
+
+// StartTwoFactorEnrollment stores a new, not yet confirmed TOTP secret for a user. It replaces any
+// earlier unconfirmed secret.
+func StartTwoFactorEnrollment(ctx context.Context, db *sql.DB, userID int, secret string) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	result, errExec := db.ExecContext(ctx, "UPDATE users SET totp_secret = $1 WHERE id = $2 AND NOT totp_enabled", secret, userID)
+	if errExec != nil {
+		return structs.Error{
+			Message: errExec.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return structs.Error{
+			Message: "two-factor authentication is already enabled",
+			Status:  http.StatusConflict,
+		}
+	}
+	return
+}
+
+// RetrieveTwoFactorSecret fetches a user's TOTP secret and whether two-factor authentication is
+// enabled. The secret is empty when the user has not enrolled.
+func RetrieveTwoFactorSecret(ctx context.Context, db *sql.DB, userID int) (secret string, enabled bool, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	errRow := db.QueryRowContext(ctx, "SELECT COALESCE(totp_secret, ''), totp_enabled FROM users WHERE id = $1", userID).Scan(&secret, &enabled)
+	if errRow != nil {
+		if errRow == sql.ErrNoRows {
+			return "", false, structs.Error{
+				Message: fmt.Sprintf("user with identifier %d not found", userID),
+				Status:  http.StatusNotFound,
+			}
+		}
+		return "", false, structs.Error{
+			Message: errRow.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return
+}
+
+// EnableTwoFactor turns on two-factor authentication for a user with a confirmed secret and
+// returns a fresh set of recovery codes. Only hashes of the codes are stored. counter is the time
+// step of the code that confirmed the secret, which cannot be used again. The user's sessions,
+// started without a second factor, end.
+func EnableTwoFactor(ctx context.Context, db *sql.DB, userID int, counter int64) (recoveryCodes []string, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	problem = inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		queryEnable := "UPDATE users SET totp_enabled = TRUE, totp_last_counter = $2 WHERE id = $1 AND totp_secret IS NOT NULL AND NOT totp_enabled"
+		result, errExec := transaction.ExecContext(ctx, queryEnable, userID, counter)
+		if errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return structs.Error{
+				Message: "two-factor authentication is already enabled or was not started",
+				Status:  http.StatusConflict,
+			}
+		}
+
+		var errCodes error
+		if recoveryCodes, errCodes = replaceRecoveryCodes(ctx, transaction, userID); errCodes != nil {
+			return structs.Error{
+				Message: errCodes.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		if errSessions := revokeSessions(ctx, transaction, userID); errSessions != nil {
+			return structs.Error{
+				Message: errSessions.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		return structs.Error{}
+	})
+	if problem.Message != "" {
+		return nil, problem
+	}
+	return recoveryCodes, structs.Error{}
+}
+
+// AcceptTwoFactorCode records that a TOTP code for time step counter was used and reports whether
+// it may be: a code is accepted once, and never after a code for a later step (RFC 6238 §5.2).
+func AcceptTwoFactorCode(ctx context.Context, db *sql.DB, userID int, counter int64) (accepted bool, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryAccept := `UPDATE users SET totp_last_counter = $2
+                    WHERE id = $1 AND totp_enabled AND (totp_last_counter IS NULL OR totp_last_counter < $2)`
+
+	result, errExec := db.ExecContext(ctx, queryAccept, userID, counter)
+	if errExec != nil {
+		return false, structs.Error{
+			Message: errExec.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, structs.Error{}
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of a user with two-factor authentication
+// enabled.
+func RegenerateRecoveryCodes(ctx context.Context, db *sql.DB, userID int) (recoveryCodes []string, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	_, enabled, problem := RetrieveTwoFactorSecret(ctx, db, userID)
+	if problem.Message != "" {
+		return nil, problem
+	}
+	if !enabled {
+		return nil, structs.Error{
+			Message: "two-factor authentication is not enabled",
+			Status:  http.StatusConflict,
+		}
+	}
+
+	problem = inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		var errCodes error
+		if recoveryCodes, errCodes = replaceRecoveryCodes(ctx, transaction, userID); errCodes != nil {
+			return structs.Error{
+				Message: errCodes.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		return structs.Error{}
+	})
+	if problem.Message != "" {
+		return nil, problem
+	}
+	return recoveryCodes, structs.Error{}
+}
+
+// DisableTwoFactor turns off two-factor authentication and removes the secret and recovery codes.
+func DisableTwoFactor(ctx context.Context, db *sql.DB, userID int) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	return inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		result, errExec := transaction.ExecContext(ctx, "UPDATE users SET totp_secret = NULL, totp_enabled = FALSE, totp_last_counter = NULL WHERE id = $1 AND totp_enabled", userID)
+		if errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return structs.Error{
+				Message: "two-factor authentication is not enabled",
+				Status:  http.StatusConflict,
+			}
+		}
+
+		if _, errExec := transaction.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		return structs.Error{}
+	})
+}
+
+// ConsumeRecoveryCode uses up one of a user's recovery codes and reports whether it was valid.
+func ConsumeRecoveryCode(ctx context.Context, db *sql.DB, userID int, code string) (valid bool, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryConsume := `UPDATE recovery_codes SET used_at = NOW()
+                     WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	result, errExec := db.ExecContext(ctx, queryConsume, userID, hashToken(normalizeRecoveryCode(code)))
+	if errExec != nil {
+		return false, structs.Error{
+			Message: errExec.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, structs.Error{}
+}
+
+// RetrieveRoleSettings fetches the security policies of every role, including roles that use the
+// defaults.
+func RetrieveRoleSettings(ctx context.Context, db *sql.DB) (collection []structs.RoleSettings, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	collection = []structs.RoleSettings{}
+	for _, role := range structs.Roles {
+		settings := structs.RoleSettings{Role: role}
+		errRow := db.QueryRowContext(ctx, "SELECT require_two_factor, modified_at, COALESCE(modified_by, 0) FROM role_settings WHERE role = $1", role).
+			Scan(&settings.RequireTwoFactor, &settings.ModifiedAt, &settings.ModifiedBy)
+		if errRow != nil && errRow != sql.ErrNoRows {
+			return []structs.RoleSettings{}, structs.Error{
+				Message: errRow.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		collection = append(collection, settings)
+	}
+	return
+}
+
+// UpdateRoleSettings stores the security policies of a role.
+func UpdateRoleSettings(ctx context.Context, db *sql.DB, settings structs.RoleSettings) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryUpsert := `INSERT INTO role_settings (role, require_two_factor, modified_by) VALUES ($1, $2, $3)
+                    ON CONFLICT (role) DO UPDATE SET require_two_factor = EXCLUDED.require_two_factor,
+                    modified_by = EXCLUDED.modified_by, modified_at = NOW()`
+
+	if _, errExec := db.ExecContext(ctx, queryUpsert, settings.Role, settings.RequireTwoFactor, settings.ModifiedBy); errExec != nil {
+		return structs.Error{
+			Message: errExec.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return
+}
+
+// RoleRequiresTwoFactor reports whether users of a role must have two-factor authentication.
+func RoleRequiresTwoFactor(ctx context.Context, db *sql.DB, role string) (required bool, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	errRow := db.QueryRowContext(ctx, "SELECT require_two_factor FROM role_settings WHERE role = $1", role).Scan(&required)
+	if errRow != nil && errRow != sql.ErrNoRows {
+		return false, structs.Error{
+			Message: errRow.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return required, structs.Error{}
+}
+
+// replaceRecoveryCodes generates new recovery codes for a user inside an open transaction,
+// dropping the old ones.
+func replaceRecoveryCodes(ctx context.Context, transaction *sql.Tx, userID int) ([]string, error) {
+	if _, errExec := transaction.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); errExec != nil {
+		return nil, errExec
+	}
+
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	recoveryCodes := make([]string, 0, recoveryCodeCount)
+	for len(recoveryCodes) < recoveryCodeCount {
+		randomBytes := make([]byte, 5)
+		if _, err := rand.Read(randomBytes); err != nil {
+			return nil, err
+		}
+		// Eight characters, shown as "abcd-efgh"
+		encoded := strings.ToLower(encoding.EncodeToString(randomBytes))
+		code := encoded[:4] + "-" + encoded[4:]
+
+		queryInsert := "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+		result, errExec := transaction.ExecContext(ctx, queryInsert, userID, hashToken(normalizeRecoveryCode(code)))
+		if errExec != nil {
+			return nil, errExec
+		}
+		if affected, _ := result.RowsAffected(); affected > 0 {
+			recoveryCodes = append(recoveryCodes, code)
+		}
+	}
+	return recoveryCodes, nil
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes so codes can be typed loosely.
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net/http"
@@ -10,53 +11,85 @@ import (
 )
 
 // StoreUser saves a new user to the database after hashing their password.
-func StoreUser(db *sql.DB, userData structs.User) (problem structs.Error) {
-	// Hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(userData.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return structs.Error{
-			Message: fmt.Sprintf("failed to hash password: %s", err.Error()),
-			Status:  http.StatusInternalServerError,
-		}
+func StoreUser(ctx context.Context, db *sql.DB, userData structs.User) (userID int, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	hashedPassword, problem := hashPassword(userData.Password)
+	if problem.Message != "" {
+		return 0, problem
 	}
 
-	queryCommand := `INSERT INTO users (username, password, created_by, modified_by) VALUES ($1, $2, $3, $4)`
+	queryCommand := `INSERT INTO users (username, password, email, created_by, modified_by) VALUES ($1, $2, NULLIF($3, ''), $4, $5) RETURNING id`
 
 	// For initial user creation, created_by/modified_by can be 0 or the user's own ID if known.
 	// For simplicity, we set it to 0 assuming system creation or self-creation.
-	_, errExec := db.Exec(queryCommand, userData.Username, string(hashedPassword), 0, 0)
+	errExec := db.QueryRowContext(ctx, queryCommand, userData.Username, hashedPassword, userData.Email, 0, 0).Scan(&userID)
 
 	if errExec != nil {
 		// Check for unique constraint violation (e.g., duplicate username)
-		if errExec.Error() == `pq: duplicate key value violates unique constraint "users_username_key"` {
-			return structs.Error{
+		if errExec.Error() == `pq: duplicate key value violates unique constraint "users_username_key"` ||
+			errExec.Error() == `pq: duplicate key value violates unique constraint "users_username_lower_key"` {
+			return 0, structs.Error{
 				Message: "username already exists",
 				Status:  http.StatusConflict, // 409 Conflict
 			}
 		}
-		return structs.Error{
+		if errExec.Error() == `pq: duplicate key value violates unique constraint "users_email_lower_key"` {
+			return 0, structs.Error{
+				Message: "email already in use",
+				Status:  http.StatusConflict,
+			}
+		}
+		return 0, structs.Error{
 			Message: errExec.Error(),
 			Status:  http.StatusInternalServerError,
 		}
 	}
 
-	return structs.Error{} // No error
+	return userID, structs.Error{} // No error
 }
 
-// FindUserByID retrieves a single user by their ID. (Useful for getting user info after auth)
-func FindUserByID(db *sql.DB, userID int) (record structs.User, problem structs.Error) {
-	querySingle := "SELECT id, username, created_at, created_by, modified_at, modified_by FROM users WHERE id = $1"
+const userColumns = `id, username, created_at, created_by, modified_at, modified_by, role,
+                      display_name, bio, avatar_url, profile_public, show_reviews, COALESCE(email, ''), email_verified, totp_enabled,
+                      deletion_scheduled_for, COALESCE(deletion_review_mode, ''), suspended_at, suspension_reason, password_reset_required`
 
-	rowResult := db.QueryRow(querySingle, userID)
-
-	errRow := rowResult.Scan(
+// scanUser reads a row selected with userColumns into a User.
+func scanUser(scanner rowScanner, record *structs.User) error {
+	errScan := scanner.Scan(
 		&record.ID,
 		&record.Username,
 		&record.CreatedAt,
 		&record.CreatedBy,
 		&record.ModifiedAt,
 		&record.ModifiedBy,
+		&record.Role,
+		&record.DisplayName,
+		&record.Bio,
+		&record.AvatarURL,
+		&record.ProfilePublic,
+		&record.ShowReviews,
+		&record.Email,
+		&record.EmailVerified,
+		&record.TwoFactorEnabled,
+		&record.DeletionScheduledFor,
+		&record.DeletionReviewMode,
+		&record.SuspendedAt,
+		&record.SuspensionReason,
+		&record.PasswordResetRequired,
 	)
+	record.Suspended = record.SuspendedAt != nil
+	return errScan
+}
+
+// FindUserByID retrieves a single user by their ID. (Useful for getting user info after auth)
+func FindUserByID(ctx context.Context, db *sql.DB, userID int) (record structs.User, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	querySingle := fmt.Sprintf("SELECT %s FROM users WHERE id = $1", userColumns)
+
+	errRow := scanUser(db.QueryRowContext(ctx, querySingle, userID), &record)
 
 	if errRow != nil {
 		if errRow == sql.ErrNoRows {
@@ -71,4 +104,175 @@ func FindUserByID(db *sql.DB, userID int) (record structs.User, problem structs.
 		}
 	}
 	return
-}
\ No newline at end of file
+}
+
+// FindLoginAccount retrieves what Basic Auth needs to sign a user in: the hashed password, the TOTP
+// secret, whether the user's role requires two-factor authentication and any admin restrictions.
+func FindLoginAccount(ctx context.Context, db *sql.DB, username string) (account structs.User, totpSecret string, roleRequiresTwoFactor bool, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryLogin := `SELECT u.id, u.username, u.password, u.role, u.totp_enabled, COALESCE(u.totp_secret, ''),
+                   COALESCE(rs.require_two_factor, FALSE), u.suspended_at IS NOT NULL, u.suspension_reason, u.password_reset_required
+                   FROM users u LEFT JOIN role_settings rs ON rs.role = u.role WHERE u.username = $1`
+	errRow := db.QueryRowContext(ctx, queryLogin, username).Scan(&account.ID, &account.Username, &account.Password, &account.Role,
+		&account.TwoFactorEnabled, &totpSecret, &roleRequiresTwoFactor, &account.Suspended, &account.SuspensionReason,
+		&account.PasswordResetRequired)
+
+	if errRow != nil {
+		if errRow == sql.ErrNoRows {
+			return account, "", false, structs.Error{
+				Message: fmt.Sprintf("user %q not found", username),
+				Status:  http.StatusNotFound,
+			}
+		}
+		return account, "", false, structs.Error{
+			Message: errRow.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return
+}
+
+// UpdateUserProfile changes the profile fields and privacy settings given in profileData. A new
+// email address (an empty one removes it) has to be verified again.
+func UpdateUserProfile(ctx context.Context, db *sql.DB, userID int, profileData structs.ProfileUpdate) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryUpdate := `UPDATE users SET display_name = COALESCE($1, display_name), bio = COALESCE($2, bio),
+                    avatar_url = COALESCE($3, avatar_url), profile_public = COALESCE($4, profile_public),
+                    show_reviews = COALESCE($5, show_reviews),
+                    email = CASE WHEN $7::text IS NULL THEN email ELSE NULLIF($7, '') END,
+                    email_verified = CASE WHEN $7::text IS NULL OR LOWER($7) = LOWER(COALESCE(email, '')) THEN email_verified ELSE FALSE END,
+                    modified_by = $6, modified_at = NOW()
+                    WHERE id = $6`
+
+	result, errExec := db.ExecContext(ctx, queryUpdate, profileData.DisplayName, profileData.Bio, profileData.AvatarURL,
+		profileData.ProfilePublic, profileData.ShowReviews, userID, profileData.Email)
+
+	if errExec != nil {
+		if errExec.Error() == `pq: duplicate key value violates unique constraint "users_email_lower_key"` {
+			return structs.Error{
+				Message: "email already in use",
+				Status:  http.StatusConflict,
+			}
+		}
+		return structs.Error{
+			Message: errExec.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return structs.Error{
+			Message: fmt.Sprintf("user with identifier %d not found", userID),
+			Status:  http.StatusNotFound,
+		}
+	}
+	return
+}
+
+// FindUserByEmail retrieves the user with the given verified email address, ignoring case. An
+// unverified address may belong to someone else, so it is not found.
+func FindUserByEmail(ctx context.Context, db *sql.DB, email string) (record structs.User, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	querySingle := `SELECT id, username, COALESCE(email, ''), email_verified FROM users WHERE LOWER(email) = LOWER($1) AND email_verified`
+
+	errRow := db.QueryRowContext(ctx, querySingle, email).Scan(&record.ID, &record.Username, &record.Email, &record.EmailVerified)
+
+	if errRow != nil {
+		if errRow == sql.ErrNoRows {
+			return record, structs.Error{
+				Message: "no user with this verified email address",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return record, structs.Error{
+			Message: fmt.Sprintf("failed to retrieve user: %s", errRow.Error()),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return
+}
+
+// ChangeUserPassword replaces a user's password after checking their current one, and ends the
+// user's sessions.
+func ChangeUserPassword(ctx context.Context, db *sql.DB, userID int, currentPassword string, newPassword string) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	var storedHash string
+	errRow := db.QueryRowContext(ctx, "SELECT password FROM users WHERE id = $1", userID).Scan(&storedHash)
+	if errRow != nil {
+		if errRow == sql.ErrNoRows {
+			return structs.Error{
+				Message: fmt.Sprintf("user with identifier %d not found", userID),
+				Status:  http.StatusNotFound,
+			}
+		}
+		return structs.Error{
+			Message: errRow.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(currentPassword)) != nil {
+		return structs.Error{
+			Message: "current password is incorrect",
+			Status:  http.StatusForbidden,
+		}
+	}
+
+	hashedPassword, problem := hashPassword(newPassword)
+	if problem.Message != "" {
+		return problem
+	}
+
+	return inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		queryUpdate := "UPDATE users SET password = $1, password_reset_required = FALSE, modified_by = $2, modified_at = NOW() WHERE id = $2"
+		if _, errExec := transaction.ExecContext(ctx, queryUpdate, hashedPassword, userID); errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		if errSessions := revokeSessions(ctx, transaction, userID); errSessions != nil {
+			return structs.Error{
+				Message: errSessions.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		return structs.Error{}
+	})
+}
+
+// RetrieveUserReviewStats counts a user's visible reviews and averages the ratings they gave.
+func RetrieveUserReviewStats(ctx context.Context, db *sql.DB, userID int) (reviewCount int, averageRating float64, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	queryStats := "SELECT COUNT(*), COALESCE(AVG(rating), 0) FROM reviews WHERE user_id = $1 AND NOT hidden"
+
+	if errQuery := db.QueryRowContext(ctx, queryStats, userID).Scan(&reviewCount, &averageRating); errQuery != nil {
+		return 0, 0, structs.Error{
+			Message: fmt.Sprintf("failed to retrieve review stats: %s", errQuery.Error()),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return
+}
+
+// hashPassword hashes a password with bcrypt for storage.
+func hashPassword(password string) (hashed string, problem structs.Error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", structs.Error{
+			Message: fmt.Sprintf("failed to hash password: %s", err.Error()),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return string(hashedPassword), structs.Error{}
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"sb-go-readrate-nabiel/structs"
+	"time"
+)
+
+// IssueUserToken creates a single-use token for the given purpose that expires after ttl. Earlier
+// unused tokens of the same purpose stop working. Only a hash of the token is stored.
+func IssueUserToken(ctx context.Context, db *sql.DB, userID int, purpose string, ttl time.Duration) (token string, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", structs.Error{
+			Message: "failed to generate token: " + err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	token = hex.EncodeToString(randomBytes)
+
+	problem = inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		queryRevoke := "UPDATE user_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL"
+		if _, errExec := transaction.ExecContext(ctx, queryRevoke, userID, purpose); errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		queryInsert := `INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4)`
+		if _, errExec := transaction.ExecContext(ctx, queryInsert, userID, purpose, hashToken(token), time.Now().Add(ttl)); errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		return structs.Error{}
+	})
+	if problem.Message != "" {
+		return "", problem
+	}
+	return token, structs.Error{}
+}
+
+// FindTokenOwner retrieves the user a valid, unused token belongs to without using it up.
+func FindTokenOwner(ctx context.Context, db *sql.DB, token string, purpose string) (record structs.User, problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	querySingle := `SELECT u.id, u.username FROM user_tokens t JOIN users u ON u.id = t.user_id
+                    WHERE t.token_hash = $1 AND t.purpose = $2 AND t.used_at IS NULL AND t.expires_at > NOW()`
+
+	errRow := db.QueryRowContext(ctx, querySingle, hashToken(token), purpose).Scan(&record.ID, &record.Username)
+	if errRow != nil {
+		if errRow == sql.ErrNoRows {
+			return record, structs.Error{
+				Message: "token is invalid or has expired",
+				Status:  http.StatusBadRequest,
+			}
+		}
+		return record, structs.Error{
+			Message: errRow.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return
+}
+
+// ResetPasswordWithToken sets a new password for the owner of a password reset token, uses the
+// token up and ends the owner's sessions.
+func ResetPasswordWithToken(ctx context.Context, db *sql.DB, token string, newPassword string) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	hashedPassword, problem := hashPassword(newPassword)
+	if problem.Message != "" {
+		return problem
+	}
+
+	return inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		userID, problem := consumeUserToken(ctx, transaction, token, structs.TokenPasswordReset)
+		if problem.Message != "" {
+			return problem
+		}
+
+		queryUpdate := "UPDATE users SET password = $1, password_reset_required = FALSE, modified_by = $2, modified_at = NOW() WHERE id = $2"
+		if _, errExec := transaction.ExecContext(ctx, queryUpdate, hashedPassword, userID); errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		if errSessions := revokeSessions(ctx, transaction, userID); errSessions != nil {
+			return structs.Error{
+				Message: errSessions.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		return structs.Error{}
+	})
+}
+
+// VerifyEmailWithToken marks the email address of an email verification token's owner as verified
+// and uses the token up.
+func VerifyEmailWithToken(ctx context.Context, db *sql.DB, token string) (problem structs.Error) {
+	ctx, done := withTimeout(ctx, &problem)
+	defer done()
+
+	return inTransaction(ctx, db, func(transaction *sql.Tx) structs.Error {
+		userID, problem := consumeUserToken(ctx, transaction, token, structs.TokenEmailVerification)
+		if problem.Message != "" {
+			return problem
+		}
+
+		queryUpdate := "UPDATE users SET email_verified = TRUE WHERE id = $1 AND email IS NOT NULL"
+		if _, errExec := transaction.ExecContext(ctx, queryUpdate, userID); errExec != nil {
+			return structs.Error{
+				Message: errExec.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		return structs.Error{}
+	})
+}
+
+// consumeUserToken marks an unused, unexpired token as used and returns its owner.
+func consumeUserToken(ctx context.Context, transaction *sql.Tx, token string, purpose string) (userID int, problem structs.Error) {
+	queryConsume := `UPDATE user_tokens SET used_at = NOW()
+                     WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+                     RETURNING user_id`
+
+	errRow := transaction.QueryRowContext(ctx, queryConsume, hashToken(token), purpose).Scan(&userID)
+	if errRow != nil {
+		if errRow == sql.ErrNoRows {
+			return 0, structs.Error{
+				Message: "token is invalid or has expired",
+				Status:  http.StatusBadRequest,
+			}
+		}
+		return 0, structs.Error{
+			Message: errRow.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	return userID, structs.Error{}
+}
+
+// hashToken returns the hex SHA-256 of a token, which is what gets stored.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,112 @@
+package reviewfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Config is the on-disk rule configuration. Rules left out of the file are disabled.
+type Config struct {
+	Blocklist *struct {
+		Action Action   `json:"action"`
+		Words  []string `json:"words"`
+	} `json:"blocklist"`
+	Links *struct {
+		Action   Action `json:"action"`
+		MaxLinks int    `json:"max_links"`
+	} `json:"links"`
+	MaxLength *struct {
+		Action        Action `json:"action"`
+		MaxCharacters int    `json:"max_characters"`
+	} `json:"max_length"`
+	RepeatedCharacters *struct {
+		Action    Action `json:"action"`
+		MaxRepeat int    `json:"max_repeat"`
+	} `json:"repeated_characters"`
+	DuplicateText *struct {
+		Action    Action `json:"action"`
+		MinLength int    `json:"min_length"`
+	} `json:"duplicate_text"`
+}
+
+// LoadFile reads a JSON rule configuration and builds the pipeline from it.
+func LoadFile(path string) (*Pipeline, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("invalid review filter config %s: %w", path, err)
+	}
+
+	pipeline, err := config.Build()
+	if err != nil {
+		return nil, fmt.Errorf("invalid review filter config %s: %w", path, err)
+	}
+	return pipeline, nil
+}
+
+// Build validates the configuration and turns it into a pipeline.
+func (config Config) Build() (*Pipeline, error) {
+	pipeline := &Pipeline{}
+
+	if config.Blocklist != nil {
+		if err := validateAction("blocklist", config.Blocklist.Action, true); err != nil {
+			return nil, err
+		}
+		if rule := NewBlocklistRule(config.Blocklist.Action, config.Blocklist.Words); rule != nil {
+			pipeline.Rules = append(pipeline.Rules, rule)
+		}
+	}
+	if config.Links != nil {
+		if err := validateAction("links", config.Links.Action, true); err != nil {
+			return nil, err
+		}
+		if config.Links.MaxLinks < 0 {
+			return nil, fmt.Errorf("links: max_links cannot be negative")
+		}
+		pipeline.Rules = append(pipeline.Rules, NewLinkLimitRule(config.Links.Action, config.Links.MaxLinks))
+	}
+	if config.RepeatedCharacters != nil {
+		if err := validateAction("repeated_characters", config.RepeatedCharacters.Action, true); err != nil {
+			return nil, err
+		}
+		if config.RepeatedCharacters.MaxRepeat < 1 {
+			return nil, fmt.Errorf("repeated_characters: max_repeat must be at least 1")
+		}
+		pipeline.Rules = append(pipeline.Rules, NewRepeatedCharactersRule(config.RepeatedCharacters.Action, config.RepeatedCharacters.MaxRepeat))
+	}
+	// Length is checked after the masks above, which can only shorten the text
+	if config.MaxLength != nil {
+		if err := validateAction("max_length", config.MaxLength.Action, true); err != nil {
+			return nil, err
+		}
+		if config.MaxLength.MaxCharacters < 1 {
+			return nil, fmt.Errorf("max_length: max_characters must be at least 1")
+		}
+		pipeline.Rules = append(pipeline.Rules, NewMaxLengthRule(config.MaxLength.Action, config.MaxLength.MaxCharacters))
+	}
+	if config.DuplicateText != nil {
+		if err := validateAction("duplicate_text", config.DuplicateText.Action, false); err != nil {
+			return nil, err
+		}
+		pipeline.Rules = append(pipeline.Rules, NewDuplicateTextRule(config.DuplicateText.Action, config.DuplicateText.MinLength))
+	}
+	return pipeline, nil
+}
+
+func validateAction(rule string, action Action, maskable bool) error {
+	switch action {
+	case Reject, Flag:
+		return nil
+	case Mask:
+		if maskable {
+			return nil
+		}
+		return fmt.Errorf("%s: action %q is not supported, expected reject or flag", rule, action)
+	}
+	return fmt.Errorf("%s: unknown action %q, expected reject, flag or mask", rule, action)
+}
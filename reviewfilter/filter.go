@@ -0,0 +1,79 @@
+package reviewfilter
+
+import "strings"
+
+// Action decides what happens to a review when a rule matches.
+type Action string
+
+const (
+	Reject Action = "reject" // Refuse the review
+	Flag   Action = "flag"   // Accept the review but send it to the moderation queue
+	Mask   Action = "mask"   // Accept the review with the offending text masked
+)
+
+// Input is the review text to check along with the context some rules need.
+type Input struct {
+	Text string
+	// DuplicateCount is the number of other reviews with the same comment.
+	DuplicateCount int
+}
+
+// Rule is a single check in the pipeline.
+type Rule interface {
+	Name() string
+	Action() Action
+	// Check returns a human readable reason when the text violates the rule.
+	Check(input Input) (reason string, violated bool)
+	// Apply masks the offending parts of text. It is only called for rules with the Mask action.
+	Apply(text string) string
+}
+
+// Outcome is the result of running a review through the pipeline.
+type Outcome struct {
+	Text     string   // The text to store, with masks applied
+	Rejected bool     // True when a Reject rule matched
+	Reasons  []string // Why the review was rejected
+	Flags    []string // Why the review should be moderated
+}
+
+// Pipeline runs its rules in order.
+type Pipeline struct {
+	Rules []Rule
+}
+
+// Run checks input against every rule. A nil Pipeline accepts everything unchanged.
+func (pipeline *Pipeline) Run(input Input) Outcome {
+	outcome := Outcome{Text: input.Text}
+	if pipeline == nil {
+		return outcome
+	}
+
+	for _, rule := range pipeline.Rules {
+		reason, violated := rule.Check(Input{Text: outcome.Text, DuplicateCount: input.DuplicateCount})
+		if !violated {
+			continue
+		}
+
+		switch rule.Action() {
+		case Reject:
+			outcome.Rejected = true
+			outcome.Reasons = append(outcome.Reasons, rule.Name()+": "+reason)
+		case Flag:
+			outcome.Flags = append(outcome.Flags, rule.Name()+": "+reason)
+		case Mask:
+			outcome.Text = rule.Apply(outcome.Text)
+		}
+	}
+	return outcome
+}
+
+// Mask returns the text Run stores for text, with every mask applied. Stored comments are masked,
+// so new comments are compared against them in this form when looking for duplicates.
+func (pipeline *Pipeline) Mask(text string) string {
+	return pipeline.Run(Input{Text: text}).Text
+}
+
+// Summary joins the rejection reasons into a single message.
+func (outcome Outcome) Summary() string {
+	return strings.Join(outcome.Reasons, "; ")
+}
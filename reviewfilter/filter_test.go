@@ -0,0 +1,80 @@
+package reviewfilter
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPipelineRun(t *testing.T) {
+	pipeline := &Pipeline{Rules: []Rule{
+		NewBlocklistRule(Mask, []string{"jelek"}),
+		NewBlocklistRule(Reject, []string{"penipu"}),
+		NewLinkLimitRule(Flag, 0),
+		NewRepeatedCharactersRule(Mask, 5),
+		NewMaxLengthRule(Reject, 30),
+		NewDuplicateTextRule(Flag, 5),
+	}}
+
+	tests := []struct {
+		name  string
+		input Input
+		want  Outcome
+	}{
+		{
+			name:  "clean text",
+			input: Input{Text: "Bagus"},
+			want:  Outcome{Text: "Bagus"},
+		},
+		{
+			name:  "masks are applied in order",
+			input: Input{Text: "Jelek!!!!!!!!"},
+			want:  Outcome{Text: "*****!!!!!"},
+		},
+		{
+			name:  "later rules check the masked text",
+			input: Input{Text: "Bagussssssssssssssssssssssssssssss"},
+			want:  Outcome{Text: "Bagusssss"},
+		},
+		{
+			name:  "every rejection is reported",
+			input: Input{Text: "penipu, jangan beli buku ini sama sekali"},
+			want: Outcome{
+				Text:     "penipu, jangan beli buku ini sama sekali",
+				Rejected: true,
+				Reasons:  []string{`blocklist: contains blocked word "penipu"`, "max_length: is 40 characters long, at most 30 allowed"},
+			},
+		},
+		{
+			name:  "flags keep the review",
+			input: Input{Text: "www.toko.example", DuplicateCount: 1},
+			want: Outcome{
+				Text:  "www.toko.example",
+				Flags: []string{"links: contains 1 links, at most 0 allowed", "duplicate_text: identical to 1 other reviews"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := pipeline.Run(test.input)
+			if got.Text != test.want.Text || got.Rejected != test.want.Rejected ||
+				!slices.Equal(got.Reasons, test.want.Reasons) || !slices.Equal(got.Flags, test.want.Flags) {
+				t.Errorf("Run(%q) = %+v, want %+v", test.input.Text, got, test.want)
+			}
+		})
+	}
+
+	if got := pipeline.Mask("jelek!!!!!!"); got != "*****!!!!!" {
+		t.Errorf("Mask = %q, want %q", got, "*****!!!!!")
+	}
+}
+
+func TestNilPipelineAcceptsEverything(t *testing.T) {
+	var pipeline *Pipeline
+	outcome := pipeline.Run(Input{Text: "apa saja", DuplicateCount: 3})
+	if outcome.Text != "apa saja" || outcome.Rejected || len(outcome.Flags) != 0 {
+		t.Errorf("Run on a nil pipeline = %+v, want the text unchanged", outcome)
+	}
+	if got := pipeline.Mask("apa saja"); got != "apa saja" {
+		t.Errorf("Mask on a nil pipeline = %q, want the text unchanged", got)
+	}
+}
@@ -0,0 +1,186 @@
+package reviewfilter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// BlocklistRule matches whole words or phrases, ignoring case.
+type BlocklistRule struct {
+	action  Action
+	pattern *regexp.Regexp
+}
+
+// NewBlocklistRule builds a rule matching any of words. It returns nil when words is empty.
+func NewBlocklistRule(action Action, words []string) *BlocklistRule {
+	escaped := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			escaped = append(escaped, regexp.QuoteMeta(word))
+		}
+	}
+	if len(escaped) == 0 {
+		return nil
+	}
+	return &BlocklistRule{
+		action:  action,
+		pattern: regexp.MustCompile(`(?i)\b(?:` + strings.Join(escaped, "|") + `)\b`),
+	}
+}
+
+func (rule *BlocklistRule) Name() string   { return "blocklist" }
+func (rule *BlocklistRule) Action() Action { return rule.action }
+
+func (rule *BlocklistRule) Check(input Input) (string, bool) {
+	match := rule.pattern.FindString(input.Text)
+	if match == "" {
+		return "", false
+	}
+	return fmt.Sprintf("contains blocked word %q", match), true
+}
+
+func (rule *BlocklistRule) Apply(text string) string {
+	return rule.pattern.ReplaceAllStringFunc(text, func(match string) string {
+		return strings.Repeat("*", utf8.RuneCountInString(match))
+	})
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// LinkLimitRule limits the number of URLs in a review.
+type LinkLimitRule struct {
+	action   Action
+	maxLinks int
+}
+
+func NewLinkLimitRule(action Action, maxLinks int) *LinkLimitRule {
+	return &LinkLimitRule{action: action, maxLinks: maxLinks}
+}
+
+func (rule *LinkLimitRule) Name() string   { return "links" }
+func (rule *LinkLimitRule) Action() Action { return rule.action }
+
+func (rule *LinkLimitRule) Check(input Input) (string, bool) {
+	count := len(linkPattern.FindAllString(input.Text, -1))
+	if count <= rule.maxLinks {
+		return "", false
+	}
+	return fmt.Sprintf("contains %d links, at most %d allowed", count, rule.maxLinks), true
+}
+
+// Apply keeps the first maxLinks links and removes the rest.
+func (rule *LinkLimitRule) Apply(text string) string {
+	seen := 0
+	return linkPattern.ReplaceAllStringFunc(text, func(match string) string {
+		seen++
+		if seen <= rule.maxLinks {
+			return match
+		}
+		return "[link removed]"
+	})
+}
+
+// MaxLengthRule limits the number of characters in a review.
+type MaxLengthRule struct {
+	action        Action
+	maxCharacters int
+}
+
+func NewMaxLengthRule(action Action, maxCharacters int) *MaxLengthRule {
+	return &MaxLengthRule{action: action, maxCharacters: maxCharacters}
+}
+
+func (rule *MaxLengthRule) Name() string   { return "max_length" }
+func (rule *MaxLengthRule) Action() Action { return rule.action }
+
+func (rule *MaxLengthRule) Check(input Input) (string, bool) {
+	length := utf8.RuneCountInString(input.Text)
+	if length <= rule.maxCharacters {
+		return "", false
+	}
+	return fmt.Sprintf("is %d characters long, at most %d allowed", length, rule.maxCharacters), true
+}
+
+// Apply truncates the text to maxCharacters.
+func (rule *MaxLengthRule) Apply(text string) string {
+	runes := []rune(text)
+	if len(runes) <= rule.maxCharacters {
+		return text
+	}
+	return string(runes[:rule.maxCharacters])
+}
+
+// RepeatedCharactersRule catches runs such as "bagusssssss" or "!!!!!!!!!!".
+type RepeatedCharactersRule struct {
+	action    Action
+	maxRepeat int
+}
+
+func NewRepeatedCharactersRule(action Action, maxRepeat int) *RepeatedCharactersRule {
+	return &RepeatedCharactersRule{action: action, maxRepeat: maxRepeat}
+}
+
+func (rule *RepeatedCharactersRule) Name() string   { return "repeated_characters" }
+func (rule *RepeatedCharactersRule) Action() Action { return rule.action }
+
+func (rule *RepeatedCharactersRule) Check(input Input) (string, bool) {
+	var previous rune
+	run := 0
+	for _, current := range input.Text {
+		if current == previous {
+			run++
+		} else {
+			previous, run = current, 1
+		}
+		if run > rule.maxRepeat {
+			return fmt.Sprintf("repeats %q more than %d times", current, rule.maxRepeat), true
+		}
+	}
+	return "", false
+}
+
+// Apply shortens every run to maxRepeat characters.
+func (rule *RepeatedCharactersRule) Apply(text string) string {
+	var builder strings.Builder
+	var previous rune
+	run := 0
+	for _, current := range text {
+		if current == previous {
+			run++
+		} else {
+			previous, run = current, 1
+		}
+		if run <= rule.maxRepeat {
+			builder.WriteRune(current)
+		}
+	}
+	return builder.String()
+}
+
+// DuplicateTextRule catches the same comment being posted on several reviews. Short comments
+// such as "Bagus!" are ignored.
+type DuplicateTextRule struct {
+	action    Action
+	minLength int
+}
+
+func NewDuplicateTextRule(action Action, minLength int) *DuplicateTextRule {
+	return &DuplicateTextRule{action: action, minLength: minLength}
+}
+
+func (rule *DuplicateTextRule) Name() string   { return "duplicate_text" }
+func (rule *DuplicateTextRule) Action() Action { return rule.action }
+
+func (rule *DuplicateTextRule) Check(input Input) (string, bool) {
+	if input.DuplicateCount == 0 || utf8.RuneCountInString(strings.TrimSpace(input.Text)) < rule.minLength {
+		return "", false
+	}
+	return fmt.Sprintf("identical to %d other reviews", input.DuplicateCount), true
+}
+
+// Apply leaves the text unchanged; duplicates cannot be masked.
+func (rule *DuplicateTextRule) Apply(text string) string {
+	return text
+}
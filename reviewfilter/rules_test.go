@@ -0,0 +1,47 @@
+package reviewfilter
+
+import "testing"
+
+func TestRules(t *testing.T) {
+	blocklist := NewBlocklistRule(Mask, []string{"spoiler alert", " jelek ", ""})
+
+	tests := []struct {
+		name         string
+		rule         Rule
+		input        Input
+		wantViolated bool
+		wantReason   string
+		wantApplied  string
+	}{
+		{"blocklist word, ignoring case", blocklist, Input{Text: "Buku JELEK sekali"}, true, `contains blocked word "JELEK"`, "Buku ***** sekali"},
+		{"blocklist phrase", blocklist, Input{Text: "spoiler alert: dia mati"}, true, `contains blocked word "spoiler alert"`, "*************: dia mati"},
+		{"blocklist only whole words", blocklist, Input{Text: "kejelekan"}, false, "", "kejelekan"},
+		{"links within the limit", NewLinkLimitRule(Mask, 1), Input{Text: "lihat https://a.example"}, false, "", "lihat https://a.example"},
+		{"links over the limit", NewLinkLimitRule(Mask, 1), Input{Text: "https://a.example dan www.b.example"}, true, "contains 2 links, at most 1 allowed", "https://a.example dan [link removed]"},
+		{"no links allowed", NewLinkLimitRule(Reject, 0), Input{Text: "HTTP://a.example"}, true, "contains 1 links, at most 0 allowed", "[link removed]"},
+		{"length at the limit", NewMaxLengthRule(Mask, 5), Input{Text: "héllo"}, false, "", "héllo"},
+		{"length over the limit, in characters", NewMaxLengthRule(Mask, 5), Input{Text: "héllo!"}, true, "is 6 characters long, at most 5 allowed", "héllo"},
+		{"repeats at the limit", NewRepeatedCharactersRule(Mask, 3), Input{Text: "baguss!!!"}, false, "", "baguss!!!"},
+		{"repeats over the limit", NewRepeatedCharactersRule(Mask, 3), Input{Text: "bagussssss!!!!"}, true, `repeats 's' more than 3 times`, "bagusss!!!"},
+		{"duplicate", NewDuplicateTextRule(Flag, 10), Input{Text: "Buku yang sangat bagus", DuplicateCount: 2}, true, "identical to 2 other reviews", "Buku yang sangat bagus"},
+		{"no duplicate", NewDuplicateTextRule(Flag, 10), Input{Text: "Buku yang sangat bagus"}, false, "", "Buku yang sangat bagus"},
+		{"duplicate too short", NewDuplicateTextRule(Flag, 10), Input{Text: "  Bagus!  ", DuplicateCount: 5}, false, "", "  Bagus!  "},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reason, violated := test.rule.Check(test.input)
+			if violated != test.wantViolated || reason != test.wantReason {
+				t.Errorf("Check(%q) = (%q, %v), want (%q, %v)", test.input.Text, reason, violated, test.wantReason, test.wantViolated)
+			}
+			if got := test.rule.Apply(test.input.Text); got != test.wantApplied {
+				t.Errorf("Apply(%q) = %q, want %q", test.input.Text, got, test.wantApplied)
+			}
+		})
+	}
+}
+
+func TestNewBlocklistRuleWithoutWords(t *testing.T) {
+	if rule := NewBlocklistRule(Reject, []string{" ", ""}); rule != nil {
+		t.Errorf("NewBlocklistRule without words = %v, want nil", rule)
+	}
+}
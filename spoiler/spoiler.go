@@ -0,0 +1,14 @@
+package spoiler
+
+import "regexp"
+
+// Placeholder replaces every hidden part of a review.
+const Placeholder = "[spoiler]"
+
+// markup matches inline spoilers written as ||hidden text||, which may span several lines.
+var markup = regexp.MustCompile(`(?s)\|\|(.+?)\|\|`)
+
+// Redact replaces every inline spoiler in text with Placeholder.
+func Redact(text string) string {
+	return markup.ReplaceAllString(text, Placeholder)
+}
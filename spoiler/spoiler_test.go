@@ -0,0 +1,28 @@
+package spoiler
+
+import "testing"
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"no markup", "Bukunya bagus", "Bukunya bagus"},
+		{"single spoiler", "Ternyata ||pelakunya kepala desa||!", "Ternyata [spoiler]!"},
+		{"several spoilers", "||A|| mati, ||B|| menikah", "[spoiler] mati, [spoiler] menikah"},
+		{"spanning lines", "Akhirnya:\n||dia selamat\ndan pulang||\nTamat", "Akhirnya:\n[spoiler]\nTamat"},
+		{"unclosed marker", "Lihat ||bagian akhir", "Lihat ||bagian akhir"},
+		{"unclosed after a closed spoiler", "||A|| lalu ||B", "[spoiler] lalu ||B"},
+		{"adjacent spoilers", "||A||||B||", "[spoiler][spoiler]"},
+		{"empty spoiler", "kosong |||| di sini", "kosong |||| di sini"},
+		{"single pipes", "a | b || c", "a | b || c"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Redact(test.text); got != test.want {
+				t.Errorf("Redact(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
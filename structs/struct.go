@@ -30,27 +30,326 @@ type Category struct {
 type User struct {
 	ID         int       `json:"id"`
 	Username   string    `json:"username"`
-	Password   string    `json:"password"` // This will store the hashed password
+	Password   string    `json:"-"` // This will store the hashed password; never serialized
 	CreatedAt  time.Time `json:"created_at"`
 	CreatedBy  int       `json:"created_by"` // Changed to int (refers to itself for initial creation, or 0/null)
 	ModifiedAt time.Time `json:"modified_at"`
 	ModifiedBy int       `json:"modified_by"` // Changed to int (refers to itself for initial modification, or 0/null)
+	Role       string    `json:"role"`        // "user", "moderator", "editor" or "admin"
+
+	DisplayName   string `json:"display_name"`
+	Bio           string `json:"bio"`
+	AvatarURL     string `json:"avatar_url"`
+	ProfilePublic bool   `json:"profile_public"` // Show bio and review stats on the public profile
+	ShowReviews   bool   `json:"show_reviews"`   // Show recent reviews on the public profile
+
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+
+	TwoFactorEnabled bool `json:"two_factor_enabled"`
+
+	DeletionScheduledFor *time.Time `json:"deletion_scheduled_for,omitempty"` // Set while a deletion request is pending
+	DeletionReviewMode   string     `json:"deletion_review_mode,omitempty"`
+
+	Suspended             bool       `json:"suspended"`
+	SuspendedAt           *time.Time `json:"suspended_at,omitempty"`
+	SuspensionReason      string     `json:"suspension_reason,omitempty"`
+	PasswordResetRequired bool       `json:"password_reset_required"` // Set by an admin; login is refused until the password is reset
+}
+
+// RegisterRequest is the payload of POST /register.
+type RegisterRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email"` // Optional; a verification email is sent when given
+}
+
+// AdminAction is an entry of the admin audit log.
+type AdminAction struct {
+	ID           int       `json:"id"`
+	AdminID      int       `json:"admin_id"`
+	TargetUserID int       `json:"target_user_id"` // 0 when the action is not about a user
+	Action       string    `json:"action"`
+	Detail       string    `json:"detail"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SuspensionRequest is the payload of POST /admin/users/:id/suspend.
+type SuspensionRequest struct {
+	Reason string `json:"reason" binding:"required"` // Shown to the user when they try to sign in
 }
 
+// RoleChange is the payload of PUT /admin/users/:id/role.
+type RoleChange struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// What happens to a deleted user's reviews.
+const (
+	ReviewsDelete    = "delete"
+	ReviewsAnonymize = "anonymize" // Kept with user_id 0
+)
+
+// AccountDeletionRequest is the payload of DELETE /me.
+type AccountDeletionRequest struct {
+	Reviews string `json:"reviews" binding:"required"` // "delete" or "anonymize"
+}
+
+// ActivityEntry is one thing a user did besides writing reviews, as listed in their data export.
+type ActivityEntry struct {
+	Type      string    `json:"type"` // "vote", "comment" or "report"
+	ReviewID  int       `json:"review_id"`
+	Detail    string    `json:"detail"` // The vote, comment text or report reason
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PasswordChange is the payload of POST /me/password.
+type PasswordChange struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// PasswordResetRequest is the payload of POST /password-reset.
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// PasswordResetConfirmation is the payload of POST /password-reset/confirm.
+type PasswordResetConfirmation struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// EmailVerification is the payload of POST /verify-email.
+type EmailVerification struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ProfileUpdate is the payload of PATCH /me. Fields left out are not changed.
+type ProfileUpdate struct {
+	DisplayName   *string `json:"display_name"`
+	Bio           *string `json:"bio"`
+	AvatarURL     *string `json:"avatar_url"`
+	ProfilePublic *bool   `json:"profile_public"`
+	ShowReviews   *bool   `json:"show_reviews"`
+	Email         *string `json:"email"` // Changing it requires verifying the new address
+}
+
+// PublicProfile is what anyone can see of a user on GET /users/:id. Stats and recent reviews are
+// left out when the user has made them private.
+type PublicProfile struct {
+	ID                 int       `json:"id"`
+	Username           string    `json:"username"`
+	DisplayName        string    `json:"display_name"`
+	Bio                string    `json:"bio,omitempty"`
+	AvatarURL          string    `json:"avatar_url"`
+	CreatedAt          time.Time `json:"created_at"`
+	Private            bool      `json:"private"`
+	ReviewCount        *int      `json:"review_count,omitempty"`
+	AverageRatingGiven *float64  `json:"average_rating_given,omitempty"`
+	RecentReviews      []Review  `json:"recent_reviews,omitempty"`
+}
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleEditor    = "editor" // Maintains the book catalog
+	RoleAdmin     = "admin"
+)
+
+// Roles lists every valid user role.
+var Roles = []string{RoleUser, RoleModerator, RoleEditor, RoleAdmin}
+
+// API key scopes. Every key can read; the other scopes allow writes.
+const (
+	ScopeRead         = "read"
+	ScopeReviews      = "reviews"       // Write reviews, votes, comments and reports
+	ScopeCatalogWrite = "catalog-write" // Write books, categories and rating aspects
+)
+
+// Scopes lists every valid API key scope.
+var Scopes = []string{ScopeRead, ScopeReviews, ScopeCatalogWrite}
+
+// APIKey is a personal key for scripts and integrations. The key itself is only returned once,
+// when it is created.
+type APIKey struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name" binding:"required"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes" binding:"required"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TwoFactorEnrollment is returned by POST /me/2fa. The secret is shown only once.
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// TwoFactorConfirmation is the payload of POST /me/2fa/confirm.
+type TwoFactorConfirmation struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorCheck is the payload of DELETE /me/2fa and POST /me/2fa/recovery-codes: a current TOTP
+// code or an unused recovery code.
+type TwoFactorCheck struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// RoleSettings holds the security policies of a role.
+type RoleSettings struct {
+	Role             string    `json:"role"`
+	RequireTwoFactor bool      `json:"require_two_factor"`
+	ModifiedAt       time.Time `json:"modified_at"`
+	ModifiedBy       int       `json:"modified_by"`
+}
+
+// Purposes of the single-use tokens sent by email.
+const (
+	TokenPasswordReset     = "password_reset"
+	TokenEmailVerification = "email_verification"
+)
+
 // NEW Struct: Review
 type Review struct {
 	ID         int       `json:"id"`
 	BookID     int       `json:"book_id"`
 	UserID     int       `json:"user_id"`
-	Rating     int       `json:"rating"` // e.g., 1-5 stars
+	Rating     float64   `json:"rating"` // e.g., 0.5-5 stars in half-star steps
 	Comment    string    `json:"comment"`
 	CreatedAt  time.Time `json:"created_at"`
 	CreatedBy  int       `json:"created_by"` // Changed to int
 	ModifiedAt time.Time `json:"modified_at"`
 	ModifiedBy int       `json:"modified_by"` // Changed to int
+
+	HelpfulCount   int `json:"helpful_count"`
+	UnhelpfulCount int `json:"unhelpful_count"`
+	CommentCount   int `json:"comment_count"`
+
+	Hidden bool `json:"hidden"` // Hidden by a moderator or by too many reports
+
+	ContainsSpoilers bool   `json:"contains_spoilers"` // The whole review is a spoiler
+	CommentSafe      string `json:"comment_safe"`      // Comment with spoilers redacted
+
+	Edited        bool `json:"edited"`
+	RevisionCount int  `json:"revision_count"` // Number of prior versions
+
+	AspectRatings map[string]float64 `json:"aspect_ratings,omitempty"` // Optional sub-ratings keyed by aspect code
+}
+
+// RatingAspect is a sub-rating a review can give, such as plot or pacing.
+type RatingAspect struct {
+	ID         int       `json:"id"`
+	Code       string    `json:"code" binding:"required"`
+	Name       string    `json:"name" binding:"required"`
+	IsDefault  bool      `json:"is_default"` // Used by categories without their own aspect set
+	CreatedAt  time.Time `json:"created_at"`
+	CreatedBy  int       `json:"created_by"`
+	ModifiedAt time.Time `json:"modified_at"`
+	ModifiedBy int       `json:"modified_by"`
+}
+
+// AspectRatingSummary is the average of one aspect across a book's reviews.
+type AspectRatingSummary struct {
+	Code          string  `json:"code"`
+	Name          string  `json:"name"`
+	AverageRating float64 `json:"average_rating"`
+	RatingCount   int     `json:"rating_count"`
+}
+
+// BookRatingSummary aggregates the overall rating and every aspect rating of a book.
+type BookRatingSummary struct {
+	BookID        int                   `json:"book_id"`
+	AverageRating float64               `json:"average_rating"`
+	ReviewCount   int                   `json:"review_count"`
+	Aspects       []AspectRatingSummary `json:"aspects"`
+}
+
+// ReviewRevision is a prior version of a review, kept when the review is edited.
+type ReviewRevision struct {
+	ID               int       `json:"id"`
+	ReviewID         int       `json:"review_id"`
+	Revision         int       `json:"revision"` // 1 for the original version
+	Rating           float64   `json:"rating"`
+	Comment          string    `json:"comment"`
+	CommentSafe      string    `json:"comment_safe"`
+	ContainsSpoilers bool      `json:"contains_spoilers"`
+	WrittenAt        time.Time `json:"written_at"`  // When this version was written
+	ReplacedAt       time.Time `json:"replaced_at"` // When this version was overwritten
+}
+
+// ReviewComment is a comment on a review. Replies point at a top-level comment through ParentID;
+// replies to replies are not allowed.
+type ReviewComment struct {
+	ID         int             `json:"id"`
+	ReviewID   int             `json:"review_id"`
+	UserID     int             `json:"user_id"`
+	ParentID   *int            `json:"parent_id"`
+	Body       string          `json:"body"`
+	CreatedAt  time.Time       `json:"created_at"`
+	CreatedBy  int             `json:"created_by"`
+	ModifiedAt time.Time       `json:"modified_at"`
+	ModifiedBy int             `json:"modified_by"`
+	Replies    []ReviewComment `json:"replies,omitempty"`
+}
+
+// ReviewVote records whether a user found a review helpful or not.
+type ReviewVote struct {
+	ID         int       `json:"id"`
+	ReviewID   int       `json:"review_id"`
+	UserID     int       `json:"user_id"`
+	Helpful    *bool     `json:"helpful" binding:"required"`
+	CreatedAt  time.Time `json:"created_at"`
+	CreatedBy  int       `json:"created_by"`
+	ModifiedAt time.Time `json:"modified_at"`
+	ModifiedBy int       `json:"modified_by"`
+}
+
+// ReviewReport is a user's complaint about a review, reviewed by moderators.
+type ReviewReport struct {
+	ID         int       `json:"id"`
+	ReviewID   int       `json:"review_id"`
+	UserID     int       `json:"user_id"` // 0 when raised by the review content filter
+	Reason     string    `json:"reason" binding:"required"`
+	Status     string    `json:"status"` // "open" or "resolved"
+	CreatedAt  time.Time `json:"created_at"`
+	CreatedBy  int       `json:"created_by"`
+	ModifiedAt time.Time `json:"modified_at"`
+	ModifiedBy int       `json:"modified_by"`
+}
+
+// ModerationQueueItem is a reported review together with its open reports.
+type ModerationQueueItem struct {
+	Review  Review         `json:"review"`
+	Reports []ReviewReport `json:"reports"`
 }
 
 type Error struct {
 	Message string `json:"detail"`
 	Status  int    `json:"status"`
-}
\ No newline at end of file
+}
+
+// BookRatingStat holds the raw review aggregates for a single book, used to build the charts.
+type BookRatingStat struct {
+	BookID        int
+	CategoryID    int
+	ReviewCount   int
+	AverageRating float64
+	RecentCount   int // Reviews created inside the trending window
+}
+
+// ChartEntry is a single ranked book in a materialized chart.
+type ChartEntry struct {
+	Chart         string    `json:"chart"`
+	CategoryID    int       `json:"category_id"` // 0 for the chart across all categories
+	Rank          int       `json:"rank"`
+	BookID        int       `json:"book_id"`
+	Title         string    `json:"title"`
+	Score         float64   `json:"score"`
+	ReviewCount   int       `json:"review_count"`
+	AverageRating float64   `json:"average_rating"`
+	ComputedAt    time.Time `json:"computed_at"`
+}
@@ -0,0 +1,76 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of RFC 6238 Appendix B, "12345678901234567890", base32 encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeMatchesRFC6238Vectors(t *testing.T) {
+	// Appendix B lists 8-digit codes; the 6-digit codes are their last six digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, test := range tests {
+		got, err := Code(rfcSecret, time.Unix(test.unix, 0))
+		if err != nil {
+			t.Fatalf("Code(%d) returned error: %v", test.unix, err)
+		}
+		if got != test.want {
+			t.Errorf("Code(%d) = %s, want %s", test.unix, got, test.want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / int64(Period.Seconds())
+
+	tests := []struct {
+		name        string
+		code        string
+		wantCounter int64
+		wantOK      bool
+	}{
+		{"current period", mustCode(t, now), step, true},
+		{"previous period", mustCode(t, now.Add(-Period)), step - 1, true},
+		{"next period", mustCode(t, now.Add(Period)), step + 1, true},
+		{"outside the skew", mustCode(t, now.Add(-2*Period)), 0, false},
+		{"wrong code", "000000", 0, false},
+		{"too short", "05047", 0, false},
+		{"too long", "0504710", 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			counter, ok := Verify(rfcSecret, test.code, now)
+			if ok != test.wantOK || counter != test.wantCounter {
+				t.Errorf("Verify(%q) = (%d, %v), want (%d, %v)", test.code, counter, ok, test.wantCounter, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsInvalidSecret(t *testing.T) {
+	if _, ok := Verify("not base32!", "123456", time.Now()); ok {
+		t.Error("Verify accepted a code for an invalid secret")
+	}
+}
+
+func mustCode(t *testing.T, at time.Time) string {
+	t.Helper()
+	code, err := Code(rfcSecret, at)
+	if err != nil {
+		t.Fatalf("Code returned error: %v", err)
+	}
+	return code
+}